@@ -19,6 +19,12 @@ type Binding interface {
 	Bind(*http.Request, interface{}) error
 }
 
+// BindingUri binds the route parameters, such as ":id" in "/user/:id".
+type BindingUri interface {
+	Name() string
+	BindUri(map[string][]string, interface{}) error
+}
+
 type StructValidator interface {
 	ValidateStruct(interface{}) error
 }
@@ -26,9 +32,14 @@ type StructValidator interface {
 var Validator StructValidator = &DefaultValidator{}
 
 var (
-	JSON = &JSONBinding{}
-	XML  = &XMLBinding{}
-	Form = &FormBinding{}
+	JSON      = &JSONBinding{}
+	XML       = &XMLBinding{}
+	Form      = &FormBinding{}
+	Multipart = &MultipartBinding{MaxMemory: 32 << 20}
+	Query     = &QueryBinding{}
+	Header    = &HeaderBinding{}
+	Cookie    = &CookieBinding{}
+	Uri       = &UriBinding{}
 )
 
 func Default(method, contentType string) Binding {
@@ -40,7 +51,8 @@ func Default(method, contentType string) Binding {
 			return JSON
 		case MIMEXML, MIMEXML2:
 			return XML
-			//case MIMEPOSTForm, MIMEMultipartPOSTForm:
+		case MIMEMultipartPOSTForm:
+			return Multipart
 		default:
 			return Form
 		}
@@ -0,0 +1,132 @@
+package binding
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/bluesuncorp/validator.v5"
+)
+
+type pageQuery struct {
+	Page    int        `form:"page" binding:"required"`
+	Tags    []string   `form:"tag"`
+	Limit   *int       `form:"limit"`
+	Since   time.Time  `form:"since" time_format:"2006-01-02"`
+	Until   *time.Time `form:"until" time_format:"unix"`
+	Ignored string     `form:"-"`
+	Filter  struct {
+		Name string `form:"name"`
+	}
+	Sort *struct {
+		Field string `form:"sort"`
+	}
+}
+
+func TestQueryBinding(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/?page=2&tag=a&tag=b&limit=10&since=2015-06-01&until=1433116800&Ignored=x&name=gin", nil)
+	var obj pageQuery
+	assert.NoError(t, Query.Bind(req, &obj))
+	assert.Equal(t, obj.Page, 2)
+	assert.Equal(t, obj.Tags, []string{"a", "b"})
+	assert.Equal(t, *obj.Limit, 10)
+	assert.Equal(t, obj.Since, time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, obj.Until.Unix(), int64(1433116800))
+	assert.Empty(t, obj.Ignored)
+	assert.Equal(t, obj.Filter.Name, "gin")
+	assert.Nil(t, obj.Sort)
+
+	req, _ = http.NewRequest("GET", "/?sort=name&page=x", nil)
+	assert.Error(t, Query.Bind(req, &pageQuery{}))
+
+	req, _ = http.NewRequest("GET", "/?sort=name", nil)
+	obj = pageQuery{}
+	err := Query.Bind(req, &obj)
+	assert.Equal(t, obj.Sort.Field, "name")
+	errs, ok := AsValidationErrors(err)
+	assert.True(t, ok)
+	assert.Equal(t, errs.Fields(), map[string]string{"Page": "required"})
+}
+
+func TestQueryBindingIgnoresBody(t *testing.T) {
+	req, _ := http.NewRequest("POST", "/?page=1", strings.NewReader("page=2"))
+	req.Header.Set("Content-Type", MIMEPOSTForm)
+	var obj pageQuery
+	assert.NoError(t, Query.Bind(req, &obj))
+	assert.Equal(t, obj.Page, 1)
+}
+
+func TestHeaderBinding(t *testing.T) {
+	var obj struct {
+		RequestID string  `header:"x-request-id" binding:"required"`
+		Rate      float64 `header:"X-Rate"`
+		Accept    []string
+	}
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Set("X-Request-Id", "42")
+	req.Header.Set("X-Rate", "0.5")
+	req.Header.Add("Accept", "text/html")
+	req.Header.Add("Accept", "application/json")
+	assert.NoError(t, Header.Bind(req, &obj))
+	assert.Equal(t, obj.RequestID, "42")
+	assert.Equal(t, obj.Rate, 0.5)
+	assert.Equal(t, obj.Accept, []string{"text/html", "application/json"})
+}
+
+func TestCookieBinding(t *testing.T) {
+	var obj struct {
+		Session string `cookie:"session" binding:"required"`
+		Theme   string `cookie:"theme"`
+	}
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	assert.NoError(t, Cookie.Bind(req, &obj))
+	assert.Equal(t, obj.Session, "abc")
+	assert.Empty(t, obj.Theme)
+
+	req, _ = http.NewRequest("GET", "/", nil)
+	obj.Session = ""
+	assert.Error(t, Cookie.Bind(req, &obj))
+}
+
+func TestUriBinding(t *testing.T) {
+	var obj struct {
+		ID   uint64 `uri:"id" binding:"required"`
+		Name string `uri:"name"`
+	}
+	assert.NoError(t, Uri.BindUri(map[string][]string{"id": {"7"}, "name": {"gin"}}, &obj))
+	assert.Equal(t, obj.ID, uint64(7))
+	assert.Equal(t, obj.Name, "gin")
+	assert.Error(t, Uri.BindUri(map[string][]string{"id": {"-1"}}, &obj))
+}
+
+func TestValidationErrors(t *testing.T) {
+	var obj Struct3
+	err := validate(&obj)
+	// the error type of the validator is kept
+	assert.IsType(t, &validator.StructErrors{}, err)
+
+	errs, ok := AsValidationErrors(err)
+	assert.True(t, ok)
+	assert.Equal(t, errs.Fields(), map[string]string{
+		"RequiredInteger":             "required",
+		"RequiredString":              "required",
+		"RequiredAnotherStruct.Value": "required",
+		"RequiredBasicSlice":          "required",
+		"RequiredComplexSlice":        "required",
+		"RequiredBoolean":             "required",
+	})
+	assert.Equal(t, errs[0].Field, "RequiredAnotherStruct.Value")
+	assert.Contains(t, err.Error(), `Field validation for "RequiredString" failed on the "required" tag`)
+	assert.Contains(t, errs.Error(), `Field validation for "RequiredString" failed on the "required" tag`)
+
+	wrapped, ok := AsValidationErrors(fmt.Errorf("bind: %w", errs))
+	assert.True(t, ok)
+	assert.Equal(t, errs, wrapped)
+	_, ok = AsValidationErrors(errors.New("other"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,24 @@
+package binding
+
+import (
+	"net/http"
+)
+
+// CookieBinding binds the request cookies by the `cookie` tag or the field name.
+type CookieBinding struct{}
+
+func (this *CookieBinding) Name() string {
+	return "cookie"
+}
+
+func (this *CookieBinding) Bind(req *http.Request, obj interface{}) error {
+	cookies := make(map[string][]string)
+	for _, cookie := range req.Cookies() {
+		cookies[cookie.Name] = append(cookies[cookie.Name], cookie.Value)
+	}
+	if err := mapFormByTag(obj, cookies, "cookie"); err != nil {
+		return err
+	}
+
+	return validate(obj)
+}
@@ -1,8 +1,11 @@
 package binding
 
 import (
+	"errors"
 	"gopkg.in/bluesuncorp/validator.v5"
 	"reflect"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -13,11 +16,48 @@ type DefaultValidator struct {
 
 var _ StructValidator = &DefaultValidator{}
 
+// ValidationErrors holds every field that failed the validation, sorted by field.
+// Fields of nested structs are named by their path, such as "Address.Street".
+// The error of DefaultValidator stays a *validator.StructErrors, use AsValidationErrors to get them.
+type ValidationErrors []*validator.FieldError
+
+// AsValidationErrors returns the fields that failed the validation reported by err,
+// a *validator.StructErrors or a ValidationErrors, possibly wrapped.
+func AsValidationErrors(err error) (ValidationErrors, bool) {
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		return errs, true
+	}
+	var structErrs *validator.StructErrors
+	if errors.As(err, &structErrs) {
+		return newValidationErrors(structErrs), true
+	}
+	return nil, false
+}
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Fields returns the failed tag by field.
+func (errs ValidationErrors) Fields() map[string]string {
+	fields := make(map[string]string, len(errs))
+	for _, err := range errs {
+		fields[err.Field] = err.Tag
+	}
+	return fields
+}
+
+// ValidateStruct returns a *validator.StructErrors for a struct failing the validation.
 func (this *DefaultValidator) ValidateStruct(obj interface{}) error {
 	if kindOfData(obj) == reflect.Struct {
 		this.lazyInit()
 		if err := this.validate.Struct(obj); err != nil {
-			return error(err)
+			return err
 		}
 	}
 	return nil
@@ -29,6 +69,22 @@ func (this *DefaultValidator) lazyInit() {
 	})
 }
 
+func newValidationErrors(structErrs *validator.StructErrors) ValidationErrors {
+	flat := structErrs.Flatten()
+	errs := make(ValidationErrors, 0, len(flat))
+	for _, err := range flat {
+		errs = append(errs, err)
+	}
+	sort.Sort(byField(errs))
+	return errs
+}
+
+type byField ValidationErrors
+
+func (a byField) Len() int           { return len(a) }
+func (a byField) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byField) Less(i, j int) bool { return a[i].Field < a[j].Field }
+
 func kindOfData(data interface{}) reflect.Kind {
 	value := reflect.ValueOf(data)
 	valueType := value.Kind()
@@ -2,59 +2,145 @@ package binding
 
 import (
 	"errors"
+	"net/http"
+	"net/textproto"
 	"reflect"
 	"strconv"
+	"time"
 )
 
+var timeType = reflect.TypeOf(time.Time{})
+
+// formSource gives the values of a binding source by key.
+type formSource interface {
+	get(key string) ([]string, bool)
+}
+
+type formValues map[string][]string
+
+func (form formValues) get(key string) ([]string, bool) {
+	values, ok := form[key]
+	return values, ok
+}
+
+type headerValues http.Header
+
+func (h headerValues) get(key string) ([]string, bool) {
+	values, ok := h[textproto.CanonicalMIMEHeaderKey(key)]
+	return values, ok
+}
+
 func mapForm(ptr interface{}, form map[string][]string) error {
-	typ := reflect.TypeOf(ptr).Elem()
-	val := reflect.ValueOf(ptr).Elem()
+	return mapFormByTag(ptr, form, "form")
+}
+
+// mapFormByTag maps form into the struct pointed by ptr. The key of a field is the value
+// of its tag, or its name when the tag is empty, and a tag of "-" skips the field.
+// Untagged struct fields are mapped from the same form.
+func mapFormByTag(ptr interface{}, form map[string][]string, tag string) error {
+	_, err := mapStruct(reflect.ValueOf(ptr).Elem(), formValues(form), tag)
+	return err
+}
+
+func mapHeader(ptr interface{}, h http.Header) error {
+	_, err := mapStruct(reflect.ValueOf(ptr).Elem(), headerValues(h), "header")
+	return err
+}
+
+// The mapStruct reports whether any field of val was set.
+func mapStruct(val reflect.Value, form formSource, tag string) (bool, error) {
+	typ := val.Type()
+	isSet := false
 	for i := 0; i < typ.NumField(); i++ {
 		typeField := typ.Field(i)
 		structField := val.Field(i)
-		if !structField.CanSet() {
+		if !structField.CanSet() || isFileType(typeField.Type) {
 			continue
 		}
 
-		structFieldKind := structField.Kind()
-		inputFieldName := typeField.Tag.Get("form")
-
+		inputFieldName := typeField.Tag.Get(tag)
+		if inputFieldName == "-" {
+			continue
+		}
 		if inputFieldName == "" {
 			inputFieldName = typeField.Name
-			if structFieldKind == reflect.Struct {
-				err := mapForm(structField.Addr().Interface(), form)
+			if isNestedStruct(typeField.Type) {
+				ok, err := mapNested(structField, form, tag)
 				if err != nil {
-					return err
+					return isSet, err
 				}
-
+				isSet = isSet || ok
 				continue
 			}
 		}
 
-		inputValue, exists := form[inputFieldName]
-		if !exists {
+		inputValue, exists := form.get(inputFieldName)
+		if !exists || len(inputValue) == 0 {
 			continue
 		}
+		if err := setFormField(structField, typeField, inputValue); err != nil {
+			return isSet, err
+		}
+		isSet = true
+	}
 
-		numElems := len(inputValue)
-		if structFieldKind == reflect.Slice && numElems > 0 {
-			sliceOf := structField.Type().Elem().Kind()
-			slice := reflect.MakeSlice(structField.Type(), numElems, numElems)
-			for j := 0; j < numElems; j++ {
-				if err := setWithProperType(sliceOf, inputValue[j], slice.Index(j)); err != nil {
-					return nil
-				}
-			}
-			val.Field(i).Set(slice)
-		} else {
-			if err := setWithProperType(typeField.Type.Kind(), inputValue[0], structField); err != nil {
+	return isSet, nil
+}
+
+// The mapNested maps a struct or a pointer to struct field, the pointer is only allocated
+// when one of its fields is set.
+func mapNested(field reflect.Value, form formSource, tag string) (bool, error) {
+	if field.Kind() != reflect.Ptr {
+		return mapStruct(field, form, tag)
+	}
+
+	elem := field
+	if field.IsNil() {
+		elem = reflect.New(field.Type().Elem())
+	}
+	ok, err := mapStruct(elem.Elem(), form, tag)
+	if ok && field.IsNil() {
+		field.Set(elem)
+	}
+	return ok, err
+}
+
+func isNestedStruct(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Struct && typ != timeType
+}
+
+// Uploaded files are mapped by mapFiles.
+func isFileType(typ reflect.Type) bool {
+	return typ == fileHeaderType || (typ.Kind() == reflect.Slice && typ.Elem() == fileHeaderType)
+}
+
+func setFormField(field reflect.Value, typeField reflect.StructField, values []string) error {
+	switch field.Kind() {
+	case reflect.Ptr:
+		elem := reflect.New(field.Type().Elem())
+		if err := setFormField(elem.Elem(), typeField, values); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	case reflect.Slice:
+		slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+		for j, value := range values {
+			if err := setFormField(slice.Index(j), typeField, []string{value}); err != nil {
 				return err
 			}
 		}
-
+		field.Set(slice)
+		return nil
 	}
 
-	return nil
+	if field.Type() == timeType {
+		return setTimeField(values[0], typeField, field)
+	}
+	return setWithProperType(field.Kind(), values[0], field)
 }
 
 func setWithProperType(valueKind reflect.Kind, val string, structField reflect.Value) error {
@@ -137,6 +223,38 @@ func setFloatField(val string, bitSize int, field reflect.Value) error {
 	return err
 }
 
+// The setTimeField parses val with the layout in the `time_format` tag of the field, RFC3339 by default.
+// The "unix" and "unixnano" formats parse val as an integer timestamp, and an empty val sets the zero time.
+func setTimeField(val string, typeField reflect.StructField, field reflect.Value) error {
+	if val == "" {
+		field.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+
+	format := typeField.Tag.Get("time_format")
+	switch format {
+	case "unix", "unixnano":
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		t := time.Unix(n, 0)
+		if format == "unixnano" {
+			t = time.Unix(0, n)
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	case "":
+		format = time.RFC3339
+	}
+
+	t, err := time.Parse(format, val)
+	if err == nil {
+		field.Set(reflect.ValueOf(t))
+	}
+	return err
+}
+
 // Don't pass in pointers to bind to. Can lead to bugs. See:
 // https://github.com/codegangsta/martini-contrib/issues/40
 // https://github.com/codegangsta/martini-contrib/pull/34#issuecomment-29683659
@@ -0,0 +1,21 @@
+package binding
+
+import (
+	"net/http"
+)
+
+// HeaderBinding binds the request headers by the `header` tag or the field name.
+// Header names are matched case insensitively.
+type HeaderBinding struct{}
+
+func (this *HeaderBinding) Name() string {
+	return "header"
+}
+
+func (this *HeaderBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := mapHeader(obj, req.Header); err != nil {
+		return err
+	}
+
+	return validate(obj)
+}
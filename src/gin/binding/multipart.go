@@ -0,0 +1,111 @@
+package binding
+
+import (
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+)
+
+var (
+	ErrRequestTooLarge = errors.New("multipart request too large")
+	ErrFileTooLarge    = errors.New("multipart file too large")
+)
+
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// MultipartBinding binds multipart/form-data requests. Values are mapped like Form,
+// and uploaded files are mapped into *multipart.FileHeader and []*multipart.FileHeader fields
+// by their `form` tag or field name.
+type MultipartBinding struct {
+	// MaxMemory is how many bytes of the parts are kept in memory, the rest goes to temporary files.
+	MaxMemory int64
+	// MaxRequestSize fails requests with larger bodies by ErrRequestTooLarge, 0 means no limit.
+	MaxRequestSize int64
+	// MaxFileSize fails requests with a larger file by ErrFileTooLarge, 0 means no limit.
+	MaxFileSize int64
+}
+
+func (this *MultipartBinding) Name() string {
+	return "multipart"
+}
+
+func (this *MultipartBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := ParseMultipart(req, this.MaxMemory, this.MaxRequestSize, this.MaxFileSize); err != nil {
+		return err
+	}
+	if err := mapForm(obj, req.Form); err != nil {
+		return err
+	}
+	if err := mapFiles(obj, req.MultipartForm.File); err != nil {
+		return err
+	}
+
+	return validate(obj)
+}
+
+// ParseMultipart parses the multipart body of req keeping maxMemory bytes in memory,
+// and checks the size of the request and of each file when maxRequestSize or maxFileSize are set.
+// A request parsed before is only checked.
+func ParseMultipart(req *http.Request, maxMemory int64, maxRequestSize int64, maxFileSize int64) error {
+	if req.MultipartForm == nil {
+		if maxRequestSize > 0 {
+			req.Body = http.MaxBytesReader(nil, req.Body, maxRequestSize)
+		}
+		if err := req.ParseMultipartForm(maxMemory); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return ErrRequestTooLarge
+			}
+			return err
+		}
+	}
+
+	if maxFileSize > 0 {
+		for _, files := range req.MultipartForm.File {
+			for _, file := range files {
+				if file.Size > maxFileSize {
+					return ErrFileTooLarge
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func mapFiles(ptr interface{}, files map[string][]*multipart.FileHeader) error {
+	typ := reflect.TypeOf(ptr).Elem()
+	val := reflect.ValueOf(ptr).Elem()
+	for i := 0; i < typ.NumField(); i++ {
+		typeField := typ.Field(i)
+		structField := val.Field(i)
+		if !structField.CanSet() {
+			continue
+		}
+
+		inputFieldName := typeField.Tag.Get("form")
+		if inputFieldName == "" {
+			inputFieldName = typeField.Name
+			if structField.Kind() == reflect.Struct {
+				if err := mapFiles(structField.Addr().Interface(), files); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		inputFiles := files[inputFieldName]
+		if len(inputFiles) == 0 {
+			continue
+		}
+
+		switch {
+		case typeField.Type == fileHeaderType:
+			structField.Set(reflect.ValueOf(inputFiles[0]))
+		case typeField.Type.Kind() == reflect.Slice && typeField.Type.Elem() == fileHeaderType:
+			structField.Set(reflect.ValueOf(inputFiles))
+		}
+	}
+
+	return nil
+}
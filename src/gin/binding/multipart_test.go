@@ -0,0 +1,76 @@
+package binding
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type uploadForm struct {
+	Title   string                  `form:"title" binding:"required"`
+	Avatar  *multipart.FileHeader   `form:"avatar" binding:"required"`
+	Photos  []*multipart.FileHeader `form:"photos"`
+	Missing *multipart.FileHeader   `form:"missing"`
+}
+
+func newMultipartRequest(values map[string]string, files map[string][]string) *http.Request {
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	for key, value := range values {
+		w.WriteField(key, value)
+	}
+	for key, contents := range files {
+		for i, content := range contents {
+			fw, _ := w.CreateFormFile(key, key+string(rune('0'+i))+".txt")
+			fw.Write([]byte(content))
+		}
+	}
+	w.Close()
+
+	req, _ := http.NewRequest("POST", "/", body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	return req
+}
+
+func TestMultipartBinding(t *testing.T) {
+	assert.Equal(t, Multipart, Default("POST", MIMEMultipartPOSTForm))
+	assert.Equal(t, "multipart", Multipart.Name())
+
+	req := newMultipartRequest(map[string]string{"title": "holiday"},
+		map[string][]string{"avatar": {"me"}, "photos": {"beach", "sea"}})
+	var form uploadForm
+	assert.NoError(t, Multipart.Bind(req, &form))
+	assert.Equal(t, "holiday", form.Title)
+	assert.Equal(t, "avatar0.txt", form.Avatar.Filename)
+	assert.Len(t, form.Photos, 2)
+	assert.Nil(t, form.Missing)
+
+	f, _ := form.Photos[1].Open()
+	content, _ := ioutil.ReadAll(f)
+	f.Close()
+	assert.Equal(t, "sea", string(content))
+
+	req = newMultipartRequest(map[string]string{"title": "holiday"}, nil)
+	assert.Error(t, Multipart.Bind(req, &uploadForm{}), "required file is missing")
+}
+
+func TestMultipartBindingLimits(t *testing.T) {
+	files := map[string][]string{"avatar": {"0123456789"}}
+
+	b := &MultipartBinding{MaxMemory: 1 << 20, MaxFileSize: 5}
+	err := b.Bind(newMultipartRequest(map[string]string{"title": "a"}, files), &uploadForm{})
+	assert.Equal(t, ErrFileTooLarge, err)
+
+	b = &MultipartBinding{MaxMemory: 1 << 20, MaxRequestSize: 64}
+	err = b.Bind(newMultipartRequest(map[string]string{"title": "a"}, files), &uploadForm{})
+	assert.Equal(t, ErrRequestTooLarge, err)
+
+	b = &MultipartBinding{MaxMemory: 1 << 20, MaxRequestSize: 1 << 20, MaxFileSize: 10}
+	assert.NoError(t, b.Bind(newMultipartRequest(map[string]string{"title": "a"}, files), &uploadForm{}))
+}
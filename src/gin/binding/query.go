@@ -0,0 +1,20 @@
+package binding
+
+import (
+	"net/http"
+)
+
+// QueryBinding binds only the url query of the request, by the `form` tag or the field name.
+type QueryBinding struct{}
+
+func (this *QueryBinding) Name() string {
+	return "query"
+}
+
+func (this *QueryBinding) Bind(req *http.Request, obj interface{}) error {
+	if err := mapForm(obj, req.URL.Query()); err != nil {
+		return err
+	}
+
+	return validate(obj)
+}
@@ -0,0 +1,17 @@
+package binding
+
+// UriBinding binds the route parameters by the `uri` tag or the field name.
+// The parameters are not part of the request, so it does not implement Binding.
+type UriBinding struct{}
+
+func (this *UriBinding) Name() string {
+	return "uri"
+}
+
+func (this *UriBinding) BindUri(params map[string][]string, obj interface{}) error {
+	if err := mapFormByTag(obj, params, "uri"); err != nil {
+		return err
+	}
+
+	return validate(obj)
+}
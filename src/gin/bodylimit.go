@@ -0,0 +1,40 @@
+package gin
+
+import (
+	"errors"
+	"net/http"
+)
+
+import (
+	"gin/binding"
+)
+
+// Returns a middleware limiting the request bodies to n bytes. A request with a larger
+// Content-Length is aborted with 413 Request Entity Too Large, and reading more than n bytes
+// of a body fails with a *http.MaxBytesError. When such an error is in c.Errors, as after a
+// failed c.Bind, and nothing was written, the response is 413 too.
+func BodyLimit(n int64) HandlerFunc {
+	return func(c *Context) {
+		if c.Request.ContentLength > n {
+			c.AbortWithStatus(413)
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, n)
+
+		c.Next()
+
+		if !c.Writer.Written() && bodyTooLarge(c.Errors) {
+			c.AbortWithStatus(413)
+		}
+	}
+}
+
+func bodyTooLarge(errs ErrorMsgs) bool {
+	for _, msg := range errs {
+		var tooLarge *http.MaxBytesError
+		if errors.As(msg.Err, &tooLarge) || msg.Err == binding.ErrRequestTooLarge {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,47 @@
+package gin
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyLimit(t *testing.T) {
+	router := New()
+	router.Use(BodyLimit(10))
+	router.POST("/read", func(c *Context) {
+		if _, err := ioutil.ReadAll(c.Request.Body); err != nil {
+			c.Error(err)
+			return
+		}
+		c.String(200, "ok")
+	})
+	router.POST("/bind", func(c *Context) {
+		var obj struct {
+			Name string `json:"name"`
+		}
+		c.BindJSON(&obj)
+	})
+
+	perform := func(path, body string, contentLength int64) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, ioutil.NopCloser(strings.NewReader(body)))
+		req.ContentLength = contentLength
+		req.Header.Set("Content-Type", MIMEJSON)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, 200, perform("/read", "small", 5).Code)
+	assert.Equal(t, 413, perform("/read", strings.Repeat("x", 11), 11).Code)
+	// a chunked body is only stopped when it is read
+	assert.Equal(t, 413, perform("/read", strings.Repeat("x", 11), -1).Code)
+	assert.Equal(t, 413, perform("/bind", `{"name": "too long"}`, -1).Code)
+	assert.Equal(t, 400, perform("/bind", `{"name"`, -1).Code)
+}
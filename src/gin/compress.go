@@ -0,0 +1,280 @@
+package gin
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The content types compressed when CompressConfig.ContentTypes is empty.
+var DefaultCompressContentTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/x-javascript",
+	"application/xml",
+	"image/svg+xml",
+}
+
+type CompressConfig struct {
+	// Level of gzip and deflate, gzip.DefaultCompression when 0.
+	Level int
+
+	// Responses with a body smaller than MinSize bytes are sent uncompressed, unless they are flushed.
+	MinSize int
+
+	// The content types allowed to be compressed, an entry ending by "/" matches the whole type
+	// such as "text/". DefaultCompressContentTypes when empty.
+	ContentTypes []string
+}
+
+// Returns a middleware compressing the responses by gzip or deflate, as allowed by
+// the Accept-Encoding header, with the default level and a minimum size of 1024 bytes.
+func Compress() HandlerFunc {
+	return CompressWithConfig(CompressConfig{MinSize: 1024})
+}
+
+// Returns a compression middleware with config.
+//
+// The response is buffered until MinSize bytes are written, then sent compressed if its
+// Content-Type is allowed and the handler did not set a Content-Encoding. Flush (used by
+// Stream and SSEvent) sends the buffered bytes right away, compressed if the type is allowed.
+// Inside the handlers c.Writer.Size() counts the uncompressed bytes, and after the middleware
+// it counts the bytes sent.
+func CompressWithConfig(config CompressConfig) HandlerFunc {
+	if config.Level == 0 {
+		config.Level = gzip.DefaultCompression
+	}
+	if len(config.ContentTypes) == 0 {
+		config.ContentTypes = DefaultCompressContentTypes
+	}
+	// the level is checked once, so the writers of the pools can not fail
+	if _, err := gzip.NewWriterLevel(nil, config.Level); err != nil {
+		panic(err)
+	}
+
+	pools := map[string]*sync.Pool{
+		"gzip": {New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(nil, config.Level)
+			return w
+		}},
+		"deflate": {New: func() interface{} {
+			w, _ := zlib.NewWriterLevel(nil, config.Level)
+			return w
+		}},
+	}
+
+	return func(c *Context) {
+		header := c.Writer.Header()
+		if !headerContains(header, "Vary", "Accept-Encoding") {
+			header.Add("Vary", "Accept-Encoding")
+		}
+
+		encoding := negotiateEncoding(c.Request.Header.Get("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{
+			ResponseWriter: c.Writer,
+			config:         &config,
+			encoding:       encoding,
+			pool:           pools[encoding],
+		}
+		c.Writer = w
+		defer func() {
+			w.close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// The negotiateEncoding returns "gzip", "deflate" or "" by the Accept-Encoding header,
+// gzip is preferred when both have the same quality.
+func negotiateEncoding(acceptEncoding string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, q := parseQuality(part)
+		switch name {
+		case "*":
+			name = "gzip"
+		case "gzip", "deflate":
+		default:
+			continue
+		}
+		if q > 0 && (q > bestQ || (q == bestQ && name == "gzip")) {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+func parseQuality(part string) (string, float64) {
+	fields := strings.Split(part, ";")
+	name := strings.ToLower(strings.TrimSpace(fields[0]))
+	q := 1.0
+	for _, param := range fields[1:] {
+		param = strings.TrimSpace(param)
+		if strings.HasPrefix(param, "q=") {
+			if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+				q = v
+			}
+		}
+	}
+	return name, q
+}
+
+func headerContains(header http.Header, key, value string) bool {
+	for _, v := range header[key] {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(io.Writer)
+}
+
+// The compressWriter buffers the body until it knows if it is compressed.
+type compressWriter struct {
+	ResponseWriter
+	config   *CompressConfig
+	encoding string
+	pool     *sync.Pool
+	buf      []byte
+	size     int
+	started  bool
+	cw       compressor
+}
+
+var _ ResponseWriter = &compressWriter{}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	w.size += len(data)
+	if !w.started {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.config.MinSize {
+			return len(data), nil
+		}
+		return len(data), w.start(true)
+	}
+	if w.cw != nil {
+		return w.cw.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.started {
+		w.start(false)
+	}
+}
+
+func (w *compressWriter) Size() int {
+	if !w.Written() {
+		return noWriten
+	}
+	return w.size
+}
+
+func (w *compressWriter) Written() bool {
+	return w.started || w.buf != nil || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.started {
+		w.start(true)
+	}
+	if w.cw != nil {
+		w.cw.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.started = true
+	return w.ResponseWriter.Hijack()
+}
+
+// The start writes the headers and the buffered body, compressed if compress is true
+// and the response allows it.
+func (w *compressWriter) start(compress bool) error {
+	w.started = true
+	header := w.Header()
+	if len(w.buf) > 0 && header.Get("Content-Type") == "" {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	status := w.Status()
+	compress = compress && status != http.StatusNoContent && status != http.StatusNotModified &&
+		header.Get("Content-Encoding") == "" && w.allowed(header.Get("Content-Type"))
+	if !compress {
+		w.ResponseWriter.WriteHeaderNow()
+		return w.writeBuffer(w.ResponseWriter)
+	}
+
+	header.Del("Content-Length")
+	header.Set("Content-Encoding", w.encoding)
+	w.cw = w.pool.Get().(compressor)
+	w.cw.Reset(w.ResponseWriter)
+	w.ResponseWriter.WriteHeaderNow()
+	return w.writeBuffer(w.cw)
+}
+
+func (w *compressWriter) writeBuffer(dst io.Writer) error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	_, err := dst.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+func (w *compressWriter) allowed(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	for _, allowed := range w.config.ContentTypes {
+		if mediaType == allowed || (strings.HasSuffix(allowed, "/") && strings.HasPrefix(mediaType, allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// The close sends a body smaller than MinSize uncompressed with its Content-Length,
+// or finishes the compressed body.
+func (w *compressWriter) close() {
+	if !w.started {
+		if w.buf == nil {
+			return
+		}
+		header := w.Header()
+		if header.Get("Content-Length") == "" {
+			header.Set("Content-Length", strconv.Itoa(len(w.buf)))
+		}
+		w.start(false)
+	}
+	if w.cw != nil {
+		w.cw.Close()
+		w.cw.Reset(nil)
+		w.pool.Put(w.cw)
+		w.cw = nil
+	}
+}
@@ -0,0 +1,138 @@
+package gin
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func performCompressRequest(r http.Handler, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCompress(t *testing.T) {
+	big := strings.Repeat("gin ", 1000)
+	sizes := make(map[string]int)
+
+	router := New()
+	router.Use(func(c *Context) {
+		c.Next()
+		sizes[c.Request.URL.Path] = c.Writer.Size()
+	})
+	router.Use(Compress())
+	router.GET("/big", func(c *Context) {
+		c.String(200, big)
+		assert.Equal(t, len(big), c.Writer.Size())
+	})
+	router.GET("/small", func(c *Context) {
+		c.String(200, "small")
+	})
+	router.GET("/png", func(c *Context) {
+		c.Data(200, "image/png", []byte(big))
+	})
+	router.GET("/empty", func(c *Context) {
+		c.AbortWithStatus(401)
+	})
+
+	w := performCompressRequest(router, "/big", "deflate;q=0.5, gzip")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Empty(t, w.Header().Get("Content-Length"))
+	assert.Equal(t, w.Body.Len(), sizes["/big"])
+	gr, err := gzip.NewReader(w.Body)
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(gr)
+		assert.Equal(t, big, string(body))
+	}
+
+	w = performCompressRequest(router, "/big", "gzip;q=0.5, deflate")
+	assert.Equal(t, "deflate", w.Header().Get("Content-Encoding"))
+	zr, err := zlib.NewReader(w.Body)
+	if assert.NoError(t, err) {
+		body, _ := ioutil.ReadAll(zr)
+		assert.Equal(t, big, string(body))
+	}
+
+	w = performCompressRequest(router, "/small", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "5", w.Header().Get("Content-Length"))
+	assert.Equal(t, "small", w.Body.String())
+
+	w = performCompressRequest(router, "/png", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, big, w.Body.String())
+
+	w = performCompressRequest(router, "/big", "identity, gzip;q=0")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Equal(t, big, w.Body.String())
+
+	w = performCompressRequest(router, "/empty", "gzip")
+	assert.Equal(t, 401, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Empty(t, w.Body.String())
+}
+
+type closeNotifyingRecorder struct {
+	*httptest.ResponseRecorder
+	closed chan bool
+}
+
+func (r *closeNotifyingRecorder) CloseNotify() <-chan bool {
+	return r.closed
+}
+
+func TestCompressStream(t *testing.T) {
+	router := New()
+	router.Use(CompressWithConfig(CompressConfig{MinSize: 1 << 20}))
+
+	var w *httptest.ResponseRecorder
+	router.GET("/stream", func(c *Context) {
+		i := 0
+		c.Stream(func(out io.Writer) bool {
+			if i++; i == 1 {
+				c.SSEvent("message", "hello")
+				return true
+			}
+			// the first event was flushed compressed before the handler returns
+			assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+			assert.True(t, w.Flushed)
+			gr, err := gzip.NewReader(strings.NewReader(w.Body.String()))
+			if assert.NoError(t, err) {
+				event := make([]byte, len("event: message\ndata: hello\n\n"))
+				io.ReadFull(gr, event)
+				assert.Equal(t, "event: message\ndata: hello\n\n", string(event))
+			}
+			return false
+		})
+	})
+
+	req, _ := http.NewRequest("GET", "/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(&closeNotifyingRecorder{w, make(chan bool)}, req)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	assert.Equal(t, "gzip", negotiateEncoding("gzip, deflate"))
+	assert.Equal(t, "gzip", negotiateEncoding("deflate, gzip"))
+	assert.Equal(t, "deflate", negotiateEncoding("deflate, gzip;q=0.1"))
+	assert.Equal(t, "gzip", negotiateEncoding("*"))
+	assert.Equal(t, "", negotiateEncoding("br, identity"))
+	assert.Equal(t, "", negotiateEncoding(""))
+}
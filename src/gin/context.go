@@ -3,7 +3,9 @@ package gin
 import (
 	"io"
 	"math"
+	"mime/multipart"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 )
@@ -47,6 +49,10 @@ type Context struct {
 	Keys      map[string]interface{} // authorization key-value
 	Errors    ErrorMsgs
 	Accecpted []string
+
+	// The ctx is the request scoped context: it is done when the client goes away,
+	// when a Timeout expires or when the handlers of the request have returned.
+	ctx context.Context
 }
 
 var _ context.Context = &Context{}
@@ -214,7 +220,7 @@ func (c *Context) query(key string) (string, bool) {
 
 func (c *Context) postForm(key string) (string, bool) {
 	req := c.Request
-	req.ParseMultipartForm(32 << 20) // 32 MB
+	req.ParseMultipartForm(c.maxMultipartMemory())
 	if values := req.PostForm[key]; len(values) > 0 {
 		return values[0], true
 	}
@@ -226,11 +232,59 @@ func (c *Context) postForm(key string) (string, bool) {
 	return "", false
 }
 
+// Returns the first file uploaded under the form key name.
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		return nil, err
+	}
+	if files := form.File[name]; len(files) > 0 {
+		return files[0], nil
+	}
+	return nil, http.ErrMissingFile
+}
+
+// Returns the parsed multipart form, with the values and the uploaded files.
+// Up to Engine.MaxMultipartMemory bytes are kept in memory and the rest is stored in temporary files.
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if err := c.Request.ParseMultipartForm(c.maxMultipartMemory()); err != nil {
+		return nil, err
+	}
+	return c.Request.MultipartForm, nil
+}
+
+// Saves the uploaded file to dst, which is created or truncated.
+func (c *Context) SaveUploadedFile(file *multipart.FileHeader, dst string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (c *Context) maxMultipartMemory() int64 {
+	if c.engine == nil {
+		return 32 << 20
+	}
+	return c.engine.MaxMultipartMemory
+}
+
 // This function checks the Content-Type to select a binding engine automatically,
 // Depending the "Content-Type" header different bindings are used:
 // "application/json" --> JSON binding
 // "application/xml"  --> XML binding
-// else --> returns an error
+// "multipart/form-data" --> Multipart binding, with files
+// else --> Form binding
 // if Parses the request's body as JSON if Content-Type == "application/json"  using JSON or XML  as a JSON input.
 // It decodes the json payload into the struct specified as a pointer.
 // Like ParseBody() but this method also writes a 400 error if the json is not valid.
@@ -243,7 +297,14 @@ func (c *Context) BindJSON(obj interface{}) error {
 	return c.BindWith(obj, binding.JSON)
 }
 
+// BindWith binds the request by b. The default binding.Multipart keeps up to
+// Engine.MaxMultipartMemory bytes in memory, like MultipartForm and FormFile.
 func (c *Context) BindWith(obj interface{}, b binding.Binding) error {
+	if b == binding.Binding(binding.Multipart) {
+		mb := *binding.Multipart
+		mb.MaxMemory = c.maxMultipartMemory()
+		b = &mb
+	}
 	if err := b.Bind(c.Request, obj); err != nil {
 		c.AbortWithError(400, err).SetType(ErrorTypeBind)
 		return err
@@ -252,6 +313,36 @@ func (c *Context) BindWith(obj interface{}, b binding.Binding) error {
 	return nil
 }
 
+// BindQuery binds only the url query, see BindWith.
+func (c *Context) BindQuery(obj interface{}) error {
+	return c.BindWith(obj, binding.Query)
+}
+
+// BindHeader binds the request headers by the `header` tag, see BindWith.
+func (c *Context) BindHeader(obj interface{}) error {
+	return c.BindWith(obj, binding.Header)
+}
+
+// BindCookie binds the request cookies by the `cookie` tag, see BindWith.
+func (c *Context) BindCookie(obj interface{}) error {
+	return c.BindWith(obj, binding.Cookie)
+}
+
+// BindUri binds the route parameters by the `uri` tag, and like BindWith
+// aborts with a 400 error when the binding fails.
+func (c *Context) BindUri(obj interface{}) error {
+	params := make(map[string][]string, len(c.Params))
+	for _, param := range c.Params {
+		params[param.key] = append(params[param.key], param.value)
+	}
+	if err := binding.Uri.BindUri(params, obj); err != nil {
+		c.AbortWithError(400, err).SetType(ErrorTypeBind)
+		return err
+	}
+
+	return nil
+}
+
 // Best effort algoritm to return the real client IP, it parses
 // X-Real-IP and X-Forwarded-For in order to work properly with reverse-proxies such us: nginx or haproxy.
 func (c *Context) ClientIP() string {
@@ -426,27 +517,47 @@ func (c *Context) SetAccepted(formats ...string) {
 /***** GOLANG.ORG/X/NET/CONTEXT *****/
 /************************************/
 
+// The Context is a context.Context scoped to the request, so it can be passed to downstream calls.
+// It is cancelled when the client closes the connection, when the deadline of a Timeout middleware
+// expires and when the handlers of the request have returned.
+// A Copy used in a goroutine is therefore done once the request is served.
+
 func (c *Context) Deadline() (deadline time.Time, ok bool) {
-	return
+	if c.ctx == nil {
+		return
+	}
+	return c.ctx.Deadline()
 }
 
 func (c *Context) Done() <-chan struct{} {
-	return nil
+	if c.ctx == nil {
+		return nil
+	}
+	return c.ctx.Done()
 }
 
 func (c *Context) Err() error {
-	return nil
+	if c.ctx == nil {
+		return nil
+	}
+	return c.ctx.Err()
 }
 
+// Value returns the request for key 0, the value set by Set for a string key,
+// and otherwise the value of the request context, such as the values of the http.Server.
 func (c *Context) Value(key interface{}) interface{} {
 	if key == 0 {
 		return c.Request
 	}
 
 	if keyAsString, ok := key.(string); ok {
-		val, _ := c.Get(keyAsString)
-		return val
+		if val, exists := c.Get(keyAsString); exists {
+			return val
+		}
 	}
 
+	if c.ctx != nil {
+		return c.ctx.Value(key)
+	}
 	return nil
 }
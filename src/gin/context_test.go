@@ -0,0 +1,175 @@
+package gin
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+	netcontext "golang.org/x/net/context"
+)
+
+func TestContextDoneAfterHandlers(t *testing.T) {
+	var done <-chan struct{}
+	r := New()
+	r.GET("/", func(c *Context) {
+		done = c.Done()
+		assert.NotNil(t, done)
+		assert.Nil(t, c.Err())
+	})
+	performRequest(r, "GET", "/")
+
+	select {
+	case <-done:
+	default:
+		t.Error("context should be done after the handlers return")
+	}
+}
+
+func TestContextCancelledByClient(t *testing.T) {
+	var err error
+	r := New()
+	r.GET("/", func(c *Context) {
+		err = c.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequest("GET", "/", nil)
+	r.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestContextTimeout(t *testing.T) {
+	var err error
+	r := New()
+	r.GET("/slow", Timeout(10*time.Millisecond), func(c *Context) {
+		_, ok := c.Deadline()
+		assert.True(t, ok)
+		<-c.Done()
+		err = c.Err()
+	})
+	r.GET("/fast", Timeout(time.Second), func(c *Context) {
+		c.String(200, "ok")
+	})
+
+	w := performRequest(r, "GET", "/slow")
+	assert.Equal(t, 503, w.Code)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	w = performRequest(r, "GET", "/fast")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "ok", w.Body.String())
+}
+
+func TestContextValueChain(t *testing.T) {
+	type key struct{}
+	r := New()
+	r.GET("/", func(c *Context) {
+		c.Set("user", "gin")
+		ctx := netcontext.WithValue(c, key{}, "value")
+		assert.Equal(t, "value", ctx.Value(key{}))
+		assert.Equal(t, "gin", ctx.Value("user"))
+		assert.Equal(t, c.Request, ctx.Value(0))
+		assert.Equal(t, c.Done(), ctx.Done())
+	})
+	performRequest(r, "GET", "/")
+}
+
+func TestContextFormFile(t *testing.T) {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	mw.WriteField("name", "gin")
+	fw, _ := mw.CreateFormFile("file", "test.txt")
+	fw.Write([]byte("uploaded"))
+	mw.Close()
+
+	dir, _ := ioutil.TempDir("", "gin")
+	defer os.RemoveAll(dir)
+
+	r := New()
+	r.POST("/upload", func(c *Context) {
+		file, err := c.FormFile("file")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "test.txt", file.Filename)
+			assert.NoError(t, c.SaveUploadedFile(file, filepath.Join(dir, file.Filename)))
+		}
+		_, err = c.FormFile("missing")
+		assert.Equal(t, http.ErrMissingFile, err)
+		assert.Equal(t, "gin", c.PostForm("name"))
+		c.String(200, "ok")
+	})
+
+	req, _ := http.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	content, _ := ioutil.ReadFile(filepath.Join(dir, "test.txt"))
+	assert.Equal(t, "uploaded", string(content))
+}
+
+func TestContextBindMultipartMemory(t *testing.T) {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	fw, _ := mw.CreateFormFile("file", "test.txt")
+	fw.Write([]byte("uploaded"))
+	mw.Close()
+
+	r := New()
+	r.MaxMultipartMemory = 1
+	r.POST("/upload", func(c *Context) {
+		var form struct {
+			File *multipart.FileHeader `form:"file"`
+		}
+		if assert.NoError(t, c.Bind(&form)) && assert.NotNil(t, form.File) {
+			f, err := form.File.Open()
+			assert.NoError(t, err)
+			// a file over MaxMultipartMemory is stored in a temporary file
+			_, onDisk := f.(*os.File)
+			assert.True(t, onDisk)
+			f.Close()
+		}
+	})
+
+	req, _ := http.NewRequest("POST", "/upload", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	r.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestContextBindUriAndQuery(t *testing.T) {
+	type userQuery struct {
+		ID     int    `uri:"id" binding:"required"`
+		Fields string `form:"fields"`
+		Token  string `header:"X-Token"`
+	}
+
+	r := New()
+	r.GET("/user/:id", func(c *Context) {
+		var obj userQuery
+		if c.BindUri(&obj) != nil || c.BindQuery(&obj) != nil || c.BindHeader(&obj) != nil {
+			return
+		}
+		c.String(200, "%d %s %s", obj.ID, obj.Fields, obj.Token)
+	})
+
+	req, _ := http.NewRequest("GET", "/user/7?fields=name", nil)
+	req.Header.Set("X-Token", "secret")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "7 name secret", w.Body.String())
+
+	w = performRequest(r, "GET", "/user/abc")
+	assert.Equal(t, 400, w.Code)
+}
@@ -0,0 +1,134 @@
+package gin
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type CORSConfig struct {
+	// The origins allowed, such as "https://example.com". "*" allows every origin,
+	// and is the default when AllowOriginFunc is nil too.
+	AllowOrigins []string
+
+	// AllowOriginFunc allows the origins it returns true for, in addition to AllowOrigins.
+	AllowOriginFunc func(origin string) bool
+
+	// The methods allowed to the preflight requests, GET, POST, PUT, PATCH, DELETE and HEAD by default.
+	AllowMethods []string
+
+	// The headers allowed to the preflight requests, the requested headers are allowed when empty.
+	AllowHeaders []string
+
+	// The response headers the browser lets the scripts read.
+	ExposeHeaders []string
+
+	// AllowCredentials lets the requests send cookies, the origin is then echoed instead of "*".
+	// It needs the origins to be listed in AllowOrigins or AllowOriginFunc, it can not allow every origin.
+	AllowCredentials bool
+
+	// MaxAge is how long the browser caches the preflight response.
+	MaxAge time.Duration
+}
+
+// Returns a CORS middleware allowing every origin, see CORSWithConfig.
+func CORS() HandlerFunc {
+	return CORSWithConfig(CORSConfig{})
+}
+
+// Returns a middleware implementing Cross-Origin Resource Sharing.
+// The preflight requests are answered with 204 No Content, or 403 Forbidden when the origin
+// or the method is not allowed. It should be attached with Engine.Use, so it also handles
+// the preflight requests of the paths without an OPTIONS route, even when
+// Engine.HandleMethodNotAllowed is enabled.
+// It panics when AllowCredentials is set with every origin allowed, since any site could then
+// make requests with the cookies of the user and read the responses.
+func CORSWithConfig(conf CORSConfig) HandlerFunc {
+	allowAll := conf.AllowOriginFunc == nil && len(conf.AllowOrigins) == 0
+	origins := make(map[string]bool, len(conf.AllowOrigins))
+	for _, origin := range conf.AllowOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		origins[strings.ToLower(origin)] = true
+	}
+	if allowAll && conf.AllowCredentials {
+		panic("AllowCredentials can not be used with every origin allowed, list them in AllowOrigins or AllowOriginFunc")
+	}
+	methods := conf.AllowMethods
+	if len(methods) == 0 {
+		methods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD"}
+	}
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(conf.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(conf.ExposeHeaders, ", ")
+	maxAge := ""
+	if conf.MaxAge > 0 {
+		maxAge = strconv.Itoa(int(conf.MaxAge / time.Second))
+	}
+
+	allowed := func(origin string) bool {
+		return allowAll || origins[strings.ToLower(origin)] ||
+			(conf.AllowOriginFunc != nil && conf.AllowOriginFunc(origin))
+	}
+
+	return func(c *Context) {
+		origin := c.Request.Header.Get("Origin")
+		if origin == "" {
+			return
+		}
+		header := c.Writer.Header()
+		header.Add("Vary", "Origin")
+
+		requestMethod := c.Request.Header.Get("Access-Control-Request-Method")
+		preflight := c.Request.Method == "OPTIONS" && requestMethod != ""
+		if !allowed(origin) {
+			if preflight {
+				c.AbortWithStatus(403)
+			}
+			return
+		}
+
+		if allowAll {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+		if conf.AllowCredentials {
+			header.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				header.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			return
+		}
+
+		if !containsMethod(methods, requestMethod) {
+			c.AbortWithStatus(403)
+			return
+		}
+		header.Add("Vary", "Access-Control-Request-Method")
+		header.Add("Vary", "Access-Control-Request-Headers")
+		header.Set("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders != "" {
+			header.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if requested := c.Request.Header.Get("Access-Control-Request-Headers"); requested != "" {
+			header.Set("Access-Control-Allow-Headers", requested)
+		}
+		if maxAge != "" {
+			header.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(204)
+	}
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func performCORSRequest(r http.Handler, method, path, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS(t *testing.T) {
+	router := New()
+	router.HandleMethodNotAllowed = true
+	router.Use(CORSWithConfig(CORSConfig{
+		AllowOrigins:     []string{"https://example.com"},
+		AllowMethods:     []string{"GET", "PUT"},
+		ExposeHeaders:    []string{"X-Request-ID"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}))
+	router.GET("/users", func(c *Context) {
+		c.String(200, "users")
+	})
+
+	w := performCORSRequest(router, "GET", "/users", "", nil)
+	assert.Equal(t, 200, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = performCORSRequest(router, "GET", "/users", "https://example.com", nil)
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "X-Request-ID", w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	// the preflight is answered even though /users has no OPTIONS route
+	w = performCORSRequest(router, "OPTIONS", "/users", "https://example.com", map[string]string{
+		"Access-Control-Request-Method":  "PUT",
+		"Access-Control-Request-Headers": "Content-Type",
+	})
+	assert.Equal(t, 204, w.Code)
+	assert.Equal(t, "GET, PUT", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "3600", w.Header().Get("Access-Control-Max-Age"))
+	assert.Empty(t, w.Body.String())
+
+	w = performCORSRequest(router, "OPTIONS", "/users", "https://example.com", map[string]string{
+		"Access-Control-Request-Method": "DELETE",
+	})
+	assert.Equal(t, 403, w.Code)
+
+	w = performCORSRequest(router, "OPTIONS", "/users", "https://evil.com", map[string]string{
+		"Access-Control-Request-Method": "GET",
+	})
+	assert.Equal(t, 403, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	// a plain OPTIONS request is not a preflight
+	w = performCORSRequest(router, "OPTIONS", "/users", "https://example.com", nil)
+	assert.Equal(t, 405, w.Code)
+}
+
+func TestCORSAllowAll(t *testing.T) {
+	router := New()
+	router.Use(CORS())
+	router.GET("/", func(c *Context) {})
+
+	w := performCORSRequest(router, "GET", "/", "https://any.com", nil)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestCORSCredentialsAllowAll(t *testing.T) {
+	assert.Panics(t, func() {
+		CORSWithConfig(CORSConfig{AllowCredentials: true})
+	})
+	assert.Panics(t, func() {
+		CORSWithConfig(CORSConfig{AllowOrigins: []string{"https://example.com", "*"}, AllowCredentials: true})
+	})
+
+	router := New()
+	router.Use(CORSWithConfig(CORSConfig{
+		AllowOriginFunc:  func(origin string) bool { return strings.HasSuffix(origin, ".example.com") },
+		AllowCredentials: true,
+	}))
+	router.GET("/", func(c *Context) {})
+
+	w := performCORSRequest(router, "GET", "/", "https://api.example.com", nil)
+	assert.Equal(t, "https://api.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+
+	w = performCORSRequest(router, "GET", "/", "https://evil.com", nil)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
@@ -9,6 +9,8 @@ import (
 	"gin"
 )
 
+var rooms = gin.NewSSEHub()
+
 func main() {
 	ConfigRuntime()
 	StartWorkers()
@@ -10,6 +10,7 @@ import (
 
 import (
 	"gin"
+	"github.com/manucorporat/sse"
 )
 
 func rateLimit(c *gin.Context) {
@@ -70,28 +71,34 @@ func roomPOST(c *gin.Context) {
 		"message": html.EscapeString(message),
 	}
 	messages.Add("inbound", 1)
-	room(roomid).Submit(post)
+	rooms.Publish(roomid, sse.Event{Event: "message", Data: post})
 	c.JSON(200, post)
 }
 
 func streamRoom(c *gin.Context) {
 	roomid := c.Param("roomid")
-	listener := openListener(roomid)
+	// a reconnecting browser receives the messages it missed
+	listener := rooms.Subscribe(roomid, c.Request.Header.Get("Last-Event-ID"))
 	ticker := time.NewTicker(1 * time.Second)
 	users.Add("connected", 1)
 	defer func() {
-		closeListener(roomid, listener)
+		rooms.Unsubscribe(listener)
 		ticker.Stop()
 		users.Add("disconnected", 1)
 	}()
 
 	c.Stream(func(w io.Writer) bool {
 		select {
-		case msg := <-listener:
+		case msg, ok := <-listener.Events():
+			if !ok {
+				return false
+			}
 			messages.Add("outbound", 1)
-			c.SSEvent("message", msg)
+			c.Render(-1, msg)
 		case <-ticker.C:
 			c.SSEvent("stats", Stats())
+		case <-c.Done():
+			return false
 		}
 		return true
 	})
@@ -2,14 +2,16 @@ package main
 
 import (
 	"fmt"
-	"io"
 	"math/rand"
 )
 
 import (
 	"gin"
+	"github.com/manucorporat/sse"
 )
 
+var rooms = gin.NewSSEHub()
+
 func main() {
 	router := gin.Default()
 	router.SetHTMLTemplate(html)
@@ -23,14 +25,7 @@ func main() {
 }
 
 func stream(c *gin.Context) {
-	roomid := c.Param("roomid")
-	listener := openListener(roomid)
-	defer closeListener(roomid, listener)
-
-	c.Stream(func(w io.Writer) bool {
-		c.SSEvent("message", <-listener)
-		return true
-	})
+	rooms.Stream(c, c.Param("roomid"))
 }
 
 func roomGET(c *gin.Context) {
@@ -46,7 +41,7 @@ func roomPOST(c *gin.Context) {
 	roomid := c.Param("roomid")
 	userid := c.PostForm("user")
 	message := c.PostForm("message")
-	room(roomid).Submit(userid + ": " + message)
+	rooms.Publish(roomid, sse.Event{Event: "message", Data: userid + ": " + message})
 
 	c.JSON(200, gin.H{
 		"status":  "success",
@@ -56,5 +51,5 @@ func roomPOST(c *gin.Context) {
 
 func roomDELETE(c *gin.Context) {
 	roomid := c.Param("roomid")
-	deleteBroadcast(roomid)
+	rooms.Close(roomid)
 }
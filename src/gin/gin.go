@@ -1,6 +1,7 @@
 package gin
 
 import (
+	"context"
 	"html/template"
 	"net"
 	"net/http"
@@ -32,6 +33,8 @@ type (
 		noMethod    HandlersChain
 		pool        sync.Pool
 		trees       MethodTrees
+		namedRoutes map[string]*Route
+		routeDocs   map[string]*routeDoc
 
 		// Enables automatic redirection if the current route can't be matched but a
 		// handler for the path with (without) the trailing slash exists.
@@ -58,6 +61,9 @@ type (
 		// If no other Method is allowed, the request is delegated to the NotFound
 		// handler.
 		HandleMethodNotAllowed bool
+
+		// The bytes of a multipart body kept in memory when it is parsed, the rest is stored in temporary files.
+		MaxMultipartMemory int64
 	}
 )
 
@@ -74,6 +80,7 @@ func New() *Engine {
 		RedirectTrailingSlash:  true,
 		RedirectFixedPath:      false,
 		HandleMethodNotAllowed: false,
+		MaxMultipartMemory:     32 << 20, // 32 MB
 	}
 
 	engine.RouterGroup.engine = engine
@@ -176,6 +183,7 @@ func (e *Engine) addRoute(method, path string, handlers HandlersChain) {
 // The router is attached to a http.Server and starts listening and serving HTTP requests.
 // It is a shortcut for http.ListenAndServe(addr, router)
 // Note: this method will block the calling goroutine undefinitelly unless an error happens.
+// See NewServer to serve on several listeners and shut down gracefully.
 func (e *Engine) Run(addr string) (err error) {
 	debugPrint("Listening and serving HTTP on %s\n", addr)
 	defer func() {
@@ -227,9 +235,14 @@ func (e *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	c.writermen.reset(w)
 	c.Request = req
 	c.reset()
+	// the request context is done when the client goes away, and the handlers are done with it once they return
+	ctx, cancel := context.WithCancel(req.Context())
+	c.ctx = ctx
 
 	e.handleHTTPRequest(c)
 
+	cancel()
+	c.ctx = nil
 	e.pool.Put(c)
 	debugPrint("ServeHTTP End  >> method:%s, url:%s", req.Method, req.URL.Path)
 }
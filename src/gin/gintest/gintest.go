@@ -0,0 +1,297 @@
+// Package gintest helps testing gin handlers: a Client performs requests on an engine
+// without a server and checks the responses, and NewContext with Run calls handlers
+// without the router.
+//
+//	client := gintest.New(t, router)
+//	client.POST("/login").Form(url.Values{"user": {"gin"}}).Do().
+//		ExpectStatus(200).
+//		ExpectHeader("X-Request-ID", "42").
+//		ExpectJSONPath("user.name", "gin")
+//	// the session cookie is sent with the next requests
+//	client.GET("/me").Do().ExpectStatus(200)
+package gintest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+import (
+	"gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// The base url of the requests by default. It is https so that the cookies set with Secure,
+// as most session cookies are, are sent back.
+const DefaultBaseURL = "https://gintest.local"
+
+// Client performs requests on an engine and keeps the cookies it sets, like a browser.
+type Client struct {
+	t       assert.TestingT
+	handler http.Handler
+	baseURL *url.URL
+	header  http.Header
+	jar     *cookiejar.Jar
+}
+
+// New returns a client of engine reporting the failed expectations to t.
+func New(t assert.TestingT, engine *gin.Engine) *Client {
+	jar, _ := cookiejar.New(nil)
+	baseURL, _ := url.Parse(DefaultBaseURL)
+	return &Client{
+		t:       t,
+		handler: engine,
+		baseURL: baseURL,
+		header:  make(http.Header),
+		jar:     jar,
+	}
+}
+
+// SetBaseURL sets the scheme and host of the requests, DefaultBaseURL by default, such as
+// "http://example.com:8080" for the handlers depending on the host. The cookies set with Secure
+// are not sent back to a http url, but for localhost.
+func (c *Client) SetBaseURL(rawurl string) *Client {
+	u, err := url.Parse(rawurl)
+	if err != nil || u.Host == "" {
+		c.t.Errorf("gintest: bad base url %q", rawurl)
+		return c
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	c.baseURL = u
+	return c
+}
+
+// SetHeader sets a header sent with every request of the client.
+func (c *Client) SetHeader(key, value string) *Client {
+	c.header.Set(key, value)
+	return c
+}
+
+func (c *Client) GET(path string) *Request {
+	return c.Request("GET", path)
+}
+
+func (c *Client) POST(path string) *Request {
+	return c.Request("POST", path)
+}
+
+func (c *Client) PUT(path string) *Request {
+	return c.Request("PUT", path)
+}
+
+func (c *Client) PATCH(path string) *Request {
+	return c.Request("PATCH", path)
+}
+
+func (c *Client) DELETE(path string) *Request {
+	return c.Request("DELETE", path)
+}
+
+// Request starts building a request, it is performed by Do.
+func (c *Client) Request(method, path string) *Request {
+	header := make(http.Header)
+	for key, values := range c.header {
+		header[key] = append([]string(nil), values...)
+	}
+	return &Request{client: c, method: method, path: path, header: header, query: make(url.Values)}
+}
+
+// Request is built by chaining its methods.
+type Request struct {
+	client  *Client
+	method  string
+	path    string
+	header  http.Header
+	query   url.Values
+	cookies []*http.Cookie
+	body    io.Reader
+}
+
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+func (r *Request) Query(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+func (r *Request) Cookie(name, value string) *Request {
+	r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: value})
+	return r
+}
+
+// Body sets the body and its content type.
+func (r *Request) Body(contentType string, body io.Reader) *Request {
+	r.header.Set("Content-Type", contentType)
+	r.body = body
+	return r
+}
+
+// JSON sets the body to obj encoded in JSON.
+func (r *Request) JSON(obj interface{}) *Request {
+	b, err := json.Marshal(obj)
+	if err != nil {
+		r.client.t.Errorf("gintest: can not encode the JSON body: %v", err)
+	}
+	return r.Body(gin.MIMEJSON, bytes.NewReader(b))
+}
+
+// Form sets the body to the url encoded values.
+func (r *Request) Form(values url.Values) *Request {
+	return r.Body(gin.MIMEPOSTForm, strings.NewReader(values.Encode()))
+}
+
+// Do performs the request and saves the cookies of the response in the client.
+func (r *Request) Do() *Response {
+	target := r.path
+	if len(r.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.query.Encode()
+	}
+
+	req := httptest.NewRequest(r.method, r.client.baseURL.String()+target, r.body)
+	req.Header = r.header
+	// the cookies of the request replace the ones of the client with the same name
+	overridden := make(map[string]bool, len(r.cookies))
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+		overridden[cookie.Name] = true
+	}
+	for _, cookie := range r.client.jar.Cookies(req.URL) {
+		if !overridden[cookie.Name] {
+			req.AddCookie(cookie)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.client.handler.ServeHTTP(w, req)
+	if cookies := w.Result().Cookies(); len(cookies) > 0 {
+		r.client.jar.SetCookies(req.URL, cookies)
+	}
+	return &Response{ResponseRecorder: w, t: r.client.t}
+}
+
+// Response is the recorded response of a request, its Expect methods report to the
+// client's t and can be chained.
+type Response struct {
+	*httptest.ResponseRecorder
+	t assert.TestingT
+}
+
+func (r *Response) ExpectStatus(code int) *Response {
+	assert.Equal(r.t, code, r.Code, "status of the response")
+	return r
+}
+
+func (r *Response) ExpectHeader(key, value string) *Response {
+	assert.Equal(r.t, value, r.Header().Get(key), "header "+key)
+	return r
+}
+
+func (r *Response) ExpectBody(body string) *Response {
+	assert.Equal(r.t, body, r.Body.String())
+	return r
+}
+
+func (r *Response) ExpectBodyContains(s string) *Response {
+	assert.Contains(r.t, r.Body.String(), s)
+	return r
+}
+
+// ExpectJSON checks that the body is the JSON encoding of expected.
+func (r *Response) ExpectJSON(expected interface{}) *Response {
+	var body interface{}
+	if err := r.DecodeJSON(&body); err != nil {
+		r.t.Errorf("gintest: the body is not JSON: %v", err)
+		return r
+	}
+	assert.Equal(r.t, normalizeJSON(expected), body)
+	return r
+}
+
+// ExpectJSONPath checks the value at a dot separated path of the JSON body, such as
+// "data.items.0.name". The values are compared by their JSON encoding, so numbers are
+// equal whatever their Go type.
+func (r *Response) ExpectJSONPath(path string, expected interface{}) *Response {
+	value, err := r.JSONPath(path)
+	if err != nil {
+		r.t.Errorf("gintest: %v", err)
+		return r
+	}
+	assert.Equal(r.t, normalizeJSON(expected), value, "JSON path "+path)
+	return r
+}
+
+// DecodeJSON decodes the body into obj.
+func (r *Response) DecodeJSON(obj interface{}) error {
+	return json.Unmarshal(r.Body.Bytes(), obj)
+}
+
+// JSONPath returns the value at a dot separated path of the JSON body.
+func (r *Response) JSONPath(path string) (interface{}, error) {
+	var data interface{}
+	if err := r.DecodeJSON(&data); err != nil {
+		return nil, fmt.Errorf("the body is not JSON: %v", err)
+	}
+	if path == "" {
+		return data, nil
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := data.(type) {
+		case map[string]interface{}:
+			value, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("no key %q in JSON path %s", key, path)
+			}
+			data = value
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("no index %q in JSON path %s", key, path)
+			}
+			data = v[i]
+		default:
+			return nil, fmt.Errorf("no %q in JSON path %s", key, path)
+		}
+	}
+	return data, nil
+}
+
+func normalizeJSON(v interface{}) interface{} {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var normalized interface{}
+	json.Unmarshal(b, &normalized)
+	return normalized
+}
+
+// NewContext returns a context for req recording its response, to call handlers without
+// the router. The route parameters are added by c.AddParam before the handlers are called
+// by Run. The context is done when req.Context() is: the test cancels it to play the client going away.
+func NewContext(req *http.Request) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContextWithRequest(w, req)
+	return c, w
+}
+
+// Run calls handlers as the chain of c, so c.Next and c.Abort work as under the router,
+// then writes the header, so the status set by a handler writing no body, such as by
+// c.AbortWithStatus, is recorded.
+func Run(c *gin.Context, handlers ...gin.HandlerFunc) {
+	c.RunHandlers(handlers...)
+}
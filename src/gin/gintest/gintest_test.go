@@ -0,0 +1,190 @@
+package gintest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+import (
+	"gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingT struct {
+	errors []string
+}
+
+func (t *recordingT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func newRouter() *gin.Engine {
+	router := gin.New()
+	router.POST("/login", func(c *gin.Context) {
+		http.SetCookie(c.Writer, &http.Cookie{Name: "session", Value: c.PostForm("user"), Path: "/", Secure: true, HttpOnly: true})
+		c.JSON(200, gin.H{"user": gin.H{"name": c.PostForm("user"), "roles": []string{"admin"}}})
+	})
+	router.GET("/me", func(c *gin.Context) {
+		session, err := c.Request.Cookie("session")
+		if err != nil {
+			c.AbortWithStatus(401)
+			return
+		}
+		c.String(200, "%s %s %s", session.Value, c.Query("lang"), c.Request.Header.Get("X-Version"))
+	})
+	router.POST("/api/login", func(c *gin.Context) {
+		http.SetCookie(c.Writer, &http.Cookie{Name: "token", Value: "secret", Path: "/api"})
+	})
+	router.GET("/api/me", func(c *gin.Context) {
+		if _, err := c.Request.Cookie("token"); err != nil {
+			c.AbortWithStatus(401)
+		}
+	})
+	router.GET("/token", func(c *gin.Context) {
+		if _, err := c.Request.Cookie("token"); err == nil {
+			c.AbortWithStatus(400)
+		}
+	})
+	router.PUT("/items/:id", func(c *gin.Context) {
+		var item struct {
+			Count int `json:"count"`
+		}
+		if c.BindJSON(&item) == nil {
+			c.JSON(200, gin.H{"id": c.Param("id"), "count": item.Count})
+		}
+	})
+	return router
+}
+
+func TestClient(t *testing.T) {
+	client := New(t, newRouter()).SetHeader("X-Version", "2")
+
+	client.GET("/me").Do().ExpectStatus(401)
+
+	client.POST("/login").Form(url.Values{"user": {"gin"}}).Do().
+		ExpectStatus(200).
+		ExpectHeader("Set-Cookie", "session=gin; Path=/; HttpOnly; Secure").
+		ExpectJSONPath("user.name", "gin").
+		ExpectJSONPath("user.roles.0", "admin").
+		ExpectJSON(gin.H{"user": gin.H{"name": "gin", "roles": []string{"admin"}}})
+
+	client.GET("/me").Query("lang", "en").Do().
+		ExpectStatus(200).
+		ExpectBody("gin en 2")
+
+	client.GET("/me").Cookie("session", "other").Do().ExpectBodyContains("other")
+
+	resp := client.PUT("/items/7").JSON(gin.H{"count": 3}).Do().
+		ExpectJSONPath("count", 3).
+		ExpectJSONPath("id", "7")
+	var item struct {
+		Count int `json:"count"`
+	}
+	assert.NoError(t, resp.DecodeJSON(&item))
+	assert.Equal(t, 3, item.Count)
+}
+
+func TestClientCookiePath(t *testing.T) {
+	client := New(t, newRouter())
+	client.GET("/api/me").Do().ExpectStatus(401)
+	client.POST("/api/login").Do().ExpectStatus(200)
+	client.GET("/api/me").Do().ExpectStatus(200)
+	client.GET("/token").Do().ExpectStatus(200)
+}
+
+func TestClientBaseURL(t *testing.T) {
+	router := newRouter()
+	router.GET("/host", func(c *gin.Context) {
+		c.String(200, "%s %v", c.Request.Host, c.Request.TLS != nil)
+	})
+
+	client := New(t, router)
+	client.GET("/host").Do().ExpectBody("gintest.local true")
+
+	// the secure session cookie is not sent back to a http url
+	client = New(t, router).SetBaseURL("http://example.com:8080")
+	client.GET("/host").Do().ExpectBody("example.com:8080 false")
+	client.POST("/login").Form(url.Values{"user": {"gin"}}).Do().ExpectStatus(200)
+	client.GET("/me").Do().ExpectStatus(401)
+
+	rt := &recordingT{}
+	New(rt, router).SetBaseURL("/path")
+	assert.Equal(t, 1, len(rt.errors))
+}
+
+func TestClientReportsFailures(t *testing.T) {
+	rt := &recordingT{}
+	client := New(rt, newRouter())
+	client.PUT("/items/7").JSON(gin.H{"count": 3}).Do().
+		ExpectStatus(201).
+		ExpectJSONPath("count", 4).
+		ExpectJSONPath("missing.key", 1).
+		ExpectJSONPath("id.0", 1)
+	assert.Equal(t, 4, len(rt.errors))
+
+	client.GET("/me").Do().ExpectJSONPath("", nil)
+	assert.Equal(t, 5, len(rt.errors))
+}
+
+func TestNewContext(t *testing.T) {
+	req, _ := http.NewRequest("GET", "/items/7?full=1", nil)
+	c, w := NewContext(req)
+	c.AddParam("id", "7")
+
+	func(c *gin.Context) {
+		c.JSON(200, gin.H{"id": c.Param("id"), "full": c.Query("full")})
+	}(c)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "{\"full\":\"1\",\"id\":\"7\"}\n", w.Body.String())
+
+	c, w = NewContext(req)
+	Run(c, func(c *gin.Context) {
+		c.AbortWithStatus(403)
+	}, func(c *gin.Context) {
+		t.Error("the handlers after an abort should not be called")
+	})
+	assert.Equal(t, 403, w.Code)
+
+	var calls []string
+	c, w = NewContext(req)
+	Run(c, func(c *gin.Context) {
+		calls = append(calls, "before")
+		c.Next()
+		calls = append(calls, "after")
+	}, func(c *gin.Context) {
+		calls = append(calls, "handler")
+	})
+	assert.Equal(t, []string{"before", "handler", "after"}, calls)
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestNewContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequest("GET", "/stream/room", nil)
+	c, w := NewContext(req.WithContext(ctx))
+	assert.Nil(t, c.Err())
+
+	hub := gin.NewSSEHub()
+	done := make(chan struct{})
+	go func() {
+		hub.Stream(c, "room")
+		close(done)
+	}()
+	for hub.Clients("room") == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the stream did not end when the request context was cancelled")
+	}
+	assert.Equal(t, context.Canceled, c.Err())
+	assert.Equal(t, 200, w.Code)
+}
@@ -1,8 +1,12 @@
 package gin
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
+	"text/template"
 	"time"
 )
 
@@ -41,35 +45,266 @@ func Logger() HandlerFunc {
 }
 
 func LoggerWithWriter(out io.Writer) HandlerFunc {
-	return func(c *Context) {
+	return LoggerWithConfig(LoggerConfig{Output: out})
+}
+
+// Instances a Logger middleware writing to gin.DefaultWriter the lines made by formatter.
+func LoggerWithFormatter(formatter LogFormatter) HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{Formatter: formatter})
+}
+
+type (
+	// LogFormatter makes the log line of a request, with its trailing newline.
+	LogFormatter func(params LogParams) string
+
+	// LogParams are the fields of a request given to a LogFormatter.
+	LogParams struct {
+		TimeStamp  time.Time
+		StatusCode int
+		Latency    time.Duration
+		ClientIP   string
+		Method     string
+		Path       string
+		RawQuery   string
+		// The request target as sent by the client, not decoded.
+		RequestURI string
+		Proto      string
+		UserAgent  string
+		Referer    string
+		// The user set by BasicAuth, if any.
+		User string
+		// The X-Request-ID header of the response, or of the request.
+		RequestID string
+		// The size of the request body, 0 when it is unknown.
+		RequestSize int64
+		// The size of the response body.
+		BodySize int
+		// The errors of the context matching LoggerConfig.ErrorTypes.
+		Errors ErrorMsgs
+		// The keys set on the context.
+		Keys map[string]interface{}
+	}
+
+	LoggerConfig struct {
+		// Output of the logs, gin.DefaultWriter by default.
+		Output io.Writer
+
+		// Formatter of the log lines, DefaultLogFormatter by default.
+		// See JSONLogFormatter, CombinedLogFormatter and TemplateLogFormatter.
+		Formatter LogFormatter
+
+		// The requests of these paths are not logged, such as health checks.
+		SkipPaths []string
+
+		// The types of the errors logged, ErrorTypeAny by default.
+		ErrorTypes ErrorType
+	}
+)
 
+// Instances a Logger middleware with conf.
+func LoggerWithConfig(conf LoggerConfig) HandlerFunc {
+	out := conf.Output
+	if out == nil {
+		out = DefaultWriter
+	}
+	formatter := conf.Formatter
+	if formatter == nil {
+		formatter = DefaultLogFormatter
+	}
+	errorTypes := conf.ErrorTypes
+	if errorTypes == 0 {
+		errorTypes = ErrorTypeAny
+	}
+	skip := make(map[string]bool, len(conf.SkipPaths))
+	for _, path := range conf.SkipPaths {
+		skip[path] = true
+	}
+
+	return func(c *Context) {
 		// start timer
 		start := time.Now()
 		path := c.Request.URL.Path
+		rawQuery := c.Request.URL.RawQuery
+		requestURI := c.Request.RequestURI
+		if requestURI == "" {
+			requestURI = c.Request.URL.RequestURI()
+		}
 
 		//Process request
 		c.Next()
 
+		if skip[path] {
+			return
+		}
+
 		//stop timer
 		end := time.Now()
-		latency := end.Sub(start)
-
-		clientIP := c.ClientIP()
-		method := c.Request.Method
-		statusCode := c.Writer.Status()
-		statusColor := colorForStatus(statusCode)
-		methodColor := colorForMethod(method)
-		comment := c.Errors.String()
-
-		fmt.Fprintf(out, "[GIN] %v |%s %3d %s| %13v | %s | %s %s %-7s %s\n%s",
-			end.Format("2006/01/02 - 15:04:05"),
-			statusColor, statusCode, reset,
-			latency,
-			clientIP,
-			methodColor, reset, method,
-			path,
-			comment,
-		)
+		params := LogParams{
+			TimeStamp:  end,
+			StatusCode: c.Writer.Status(),
+			Latency:    end.Sub(start),
+			ClientIP:   c.ClientIP(),
+			Method:     c.Request.Method,
+			Path:       path,
+			RawQuery:   rawQuery,
+			RequestURI: requestURI,
+			Proto:      c.Request.Proto,
+			UserAgent:  c.Request.UserAgent(),
+			Referer:    c.Request.Referer(),
+			RequestID:  c.Writer.Header().Get("X-Request-ID"),
+			BodySize:   c.Writer.Size(),
+			Errors:     c.Errors.ByType(errorTypes),
+			Keys:       c.Keys,
+		}
+		if user, ok := c.Keys[AuthUserKey].(string); ok {
+			params.User = user
+		}
+		if params.RequestID == "" {
+			params.RequestID = c.Request.Header.Get("X-Request-ID")
+		}
+		if c.Request.ContentLength > 0 {
+			params.RequestSize = c.Request.ContentLength
+		}
+		if params.BodySize < 0 {
+			params.BodySize = 0
+		}
+
+		io.WriteString(out, formatter(params))
+	}
+}
+
+// The log line of gin, colored by status and method, followed by the errors.
+func DefaultLogFormatter(params LogParams) string {
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %s | %s %s %-7s %s\n%s",
+		params.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		colorForStatus(params.StatusCode), params.StatusCode, reset,
+		params.Latency,
+		params.ClientIP,
+		colorForMethod(params.Method), reset, params.Method,
+		params.Path,
+		params.Errors.String(),
+	)
+}
+
+// One JSON object by line, the latency is in milliseconds and the errors are their messages.
+func JSONLogFormatter(params LogParams) string {
+	line, _ := json.Marshal(struct {
+		Time        string   `json:"time"`
+		Status      int      `json:"status"`
+		Latency     float64  `json:"latency_ms"`
+		ClientIP    string   `json:"client_ip"`
+		Method      string   `json:"method"`
+		Path        string   `json:"path"`
+		Query       string   `json:"query,omitempty"`
+		Proto       string   `json:"proto"`
+		UserAgent   string   `json:"user_agent,omitempty"`
+		Referer     string   `json:"referer,omitempty"`
+		User        string   `json:"user,omitempty"`
+		RequestID   string   `json:"request_id,omitempty"`
+		RequestSize int64    `json:"request_size"`
+		BodySize    int      `json:"body_size"`
+		Errors      []string `json:"errors,omitempty"`
+	}{
+		Time:        params.TimeStamp.Format(time.RFC3339),
+		Status:      params.StatusCode,
+		Latency:     float64(params.Latency) / float64(time.Millisecond),
+		ClientIP:    params.ClientIP,
+		Method:      params.Method,
+		Path:        params.Path,
+		Query:       params.RawQuery,
+		Proto:       params.Proto,
+		UserAgent:   params.UserAgent,
+		Referer:     params.Referer,
+		User:        params.User,
+		RequestID:   params.RequestID,
+		RequestSize: params.RequestSize,
+		BodySize:    params.BodySize,
+		Errors:      params.Errors.Errors(),
+	})
+	return string(line) + "\n"
+}
+
+// The Apache combined log format:
+//
+//	127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"
+//
+// The request line holds the request target as sent, and like Apache the values sent by the client
+// have their quotes, backslashes and control characters escaped, so they can not forge lines or fields.
+func CombinedLogFormatter(params LogParams) string {
+	uri := params.RequestURI
+	if uri == "" {
+		uri = params.Path
+		if params.RawQuery != "" {
+			uri += "?" + params.RawQuery
+		}
+	}
+	size := "-"
+	if params.BodySize > 0 {
+		size = strconv.Itoa(params.BodySize)
+	}
+	return fmt.Sprintf("%s - %s [%s] \"%s %s %s\" %d %s \"%s\" \"%s\"\n",
+		params.ClientIP,
+		escapeLogValue(orDash(params.User)),
+		params.TimeStamp.Format("02/Jan/2006:15:04:05 -0700"),
+		escapeLogValue(params.Method), escapeLogValue(uri), escapeLogValue(params.Proto),
+		params.StatusCode,
+		size,
+		escapeLogValue(orDash(params.Referer)),
+		escapeLogValue(orDash(params.UserAgent)),
+	)
+}
+
+// Escapes s as Apache does in its logs: quotes and backslashes are backslashed,
+// and control and non-ASCII bytes are written as \n, \t or \xhh.
+func escapeLogValue(s string) string {
+	var buf bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		b := s[i]
+		switch {
+		case b == '"' || b == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(b)
+		case b == '\b':
+			buf.WriteString(`\b`)
+		case b == '\n':
+			buf.WriteString(`\n`)
+		case b == '\r':
+			buf.WriteString(`\r`)
+		case b == '\t':
+			buf.WriteString(`\t`)
+		case b == '\v':
+			buf.WriteString(`\v`)
+		case b < 0x20 || b >= 0x7f:
+			fmt.Fprintf(&buf, `\x%02x`, b)
+		default:
+			buf.WriteByte(b)
+		}
+	}
+	return buf.String()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// Returns a LogFormatter executing a text/template on the LogParams, it panics if text is not valid.
+// A newline is added when the template output does not end by one.
+//
+//	gin.TemplateLogFormatter(`{{.ClientIP}} {{.Method}} {{.Path}} {{.StatusCode}} {{.Latency}}`)
+func TemplateLogFormatter(text string) LogFormatter {
+	tmpl := template.Must(template.New("log").Parse(text))
+	return func(params LogParams) string {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, params); err != nil {
+			return "[GIN] log template error: " + err.Error() + "\n"
+		}
+		if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+		return buf.String()
 	}
 }
 
@@ -2,7 +2,13 @@ package gin
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 import (
@@ -87,3 +93,92 @@ func TestColorForStatus(t *testing.T) {
 	assert.Equal(t, colorForStatus(404), string([]byte{27, 91, 57, 55, 59, 52, 51, 109}), "4xx should be yellow")
 	assert.Equal(t, colorForStatus(2), string([]byte{27, 91, 57, 55, 59, 52, 49, 109}), "other things should be red")
 }
+
+func TestLoggerWithConfig(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	router := New()
+	router.Use(LoggerWithConfig(LoggerConfig{
+		Output:     buffer,
+		Formatter:  JSONLogFormatter,
+		SkipPaths:  []string{"/health"},
+		ErrorTypes: ErrorTypePublic,
+	}))
+	router.GET("/health", func(c *Context) {})
+	router.POST("/example", func(c *Context) {
+		c.Error(errors.New("public")).SetType(ErrorTypePublic)
+		c.Error(errors.New("private"))
+		c.String(201, "created")
+	})
+
+	performRequest(router, "GET", "/health")
+	assert.Empty(t, buffer.String())
+
+	req, _ := http.NewRequest("POST", "/example?page=1", strings.NewReader("body"))
+	req.Header.Set("User-Agent", "gin-test")
+	req.Header.Set("Referer", "http://example.com")
+	req.Header.Set("X-Request-ID", "42")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buffer.Bytes(), &line))
+	assert.Equal(t, float64(201), line["status"])
+	assert.Equal(t, "POST", line["method"])
+	assert.Equal(t, "/example", line["path"])
+	assert.Equal(t, "page=1", line["query"])
+	assert.Equal(t, "gin-test", line["user_agent"])
+	assert.Equal(t, "http://example.com", line["referer"])
+	assert.Equal(t, "42", line["request_id"])
+	assert.Equal(t, float64(4), line["request_size"])
+	assert.Equal(t, float64(7), line["body_size"])
+	assert.Equal(t, []interface{}{"public"}, line["errors"])
+}
+
+func TestLoggerCombinedRequestURI(t *testing.T) {
+	buffer := new(bytes.Buffer)
+	router := New()
+	router.Use(LoggerWithConfig(LoggerConfig{Output: buffer, Formatter: CombinedLogFormatter}))
+	router.GET("/*path", func(c *Context) {})
+
+	req, _ := http.NewRequest("GET", "/a%0A1.2.3.4", nil)
+	req.RequestURI = "/a%0A1.2.3.4?q=%22"
+	router.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Contains(t, buffer.String(), `"GET /a%0A1.2.3.4?q=%22 HTTP/1.1"`)
+	assert.Equal(t, 1, strings.Count(buffer.String(), "\n"))
+}
+
+func TestLogFormatters(t *testing.T) {
+	params := LogParams{
+		TimeStamp:  time.Date(2000, 10, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		StatusCode: 200,
+		Latency:    time.Millisecond,
+		ClientIP:   "127.0.0.1",
+		Method:     "GET",
+		Path:       "/apache_pb.gif",
+		RawQuery:   "a=1",
+		Proto:      "HTTP/1.0",
+		User:       "frank",
+		BodySize:   2326,
+		Referer:    "http://www.example.com/start.html",
+		UserAgent:  "Mozilla/4.08",
+	}
+	assert.Equal(t, `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?a=1 HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`+"\n",
+		CombinedLogFormatter(params))
+
+	params.User, params.BodySize, params.Referer, params.UserAgent = "", 0, "", ""
+	assert.Equal(t, `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?a=1 HTTP/1.0" 200 - "-" "-"`+"\n",
+		CombinedLogFormatter(params))
+
+	// the values sent by the client can not forge lines or fields
+	params.RequestURI = "/a%0A1.2.3.4 - x"
+	params.Referer = `\"`
+	params.UserAgent = "agent\" \"x\x01\xff\n"
+	assert.Equal(t, `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /a%0A1.2.3.4 - x HTTP/1.0" 200 - "\\\"" "agent\" \"x\x01\xff\n"`+"\n",
+		CombinedLogFormatter(params))
+	params.RequestURI, params.Referer, params.UserAgent = "", "", ""
+
+	formatter := TemplateLogFormatter(`{{.Method}} {{.Path}} {{.StatusCode}} {{.Latency}}`)
+	assert.Equal(t, "GET /apache_pb.gif 200 1ms\n", formatter(params))
+	assert.Panics(t, func() {
+		TemplateLogFormatter(`{{.Method`)
+	})
+}
@@ -0,0 +1,381 @@
+package gin
+
+import (
+	"mime/multipart"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	OpenAPIInfo struct {
+		Title       string `json:"title"`
+		Version     string `json:"version"`
+		Description string `json:"description,omitempty"`
+	}
+
+	// OpenAPIDocument is an OpenAPI 3 document, see Engine.OpenAPI.
+	OpenAPIDocument struct {
+		OpenAPI    string                                  `json:"openapi"`
+		Info       OpenAPIInfo                             `json:"info"`
+		Paths      map[string]map[string]*OpenAPIOperation `json:"paths"`
+		Components OpenAPIComponents                       `json:"components"`
+	}
+
+	OpenAPIComponents struct {
+		Schemas map[string]*OpenAPISchema `json:"schemas,omitempty"`
+	}
+
+	OpenAPIOperation struct {
+		OperationID string                      `json:"operationId,omitempty"`
+		Summary     string                      `json:"summary,omitempty"`
+		Tags        []string                    `json:"tags,omitempty"`
+		Parameters  []*OpenAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *OpenAPIRequestBody         `json:"requestBody,omitempty"`
+		Responses   map[string]*OpenAPIResponse `json:"responses"`
+	}
+
+	OpenAPIParameter struct {
+		Name     string         `json:"name"`
+		In       string         `json:"in"`
+		Required bool           `json:"required,omitempty"`
+		Schema   *OpenAPISchema `json:"schema"`
+	}
+
+	OpenAPIRequestBody struct {
+		Required bool                        `json:"required"`
+		Content  map[string]OpenAPIMediaType `json:"content"`
+	}
+
+	OpenAPIResponse struct {
+		Description string                      `json:"description"`
+		Content     map[string]OpenAPIMediaType `json:"content,omitempty"`
+	}
+
+	OpenAPIMediaType struct {
+		Schema *OpenAPISchema `json:"schema"`
+	}
+
+	OpenAPISchema struct {
+		Ref                  string                    `json:"$ref,omitempty"`
+		Type                 string                    `json:"type,omitempty"`
+		Format               string                    `json:"format,omitempty"`
+		Items                *OpenAPISchema            `json:"items,omitempty"`
+		Properties           map[string]*OpenAPISchema `json:"properties,omitempty"`
+		AdditionalProperties *OpenAPISchema            `json:"additionalProperties,omitempty"`
+		Required             []string                  `json:"required,omitempty"`
+	}
+
+	// routeDoc holds what is known of a route by method and path: its name and its documentation.
+	routeDoc struct {
+		name      string
+		summary   string
+		tags      []string
+		request   reflect.Type
+		responses map[int]reflect.Type
+	}
+)
+
+func (r *Route) doc() *routeDoc {
+	e := r.engine
+	if e.routeDocs == nil {
+		e.routeDocs = make(map[string]*routeDoc)
+	}
+	key := r.Method + " " + r.Path
+	doc, ok := e.routeDocs[key]
+	if !ok {
+		doc = &routeDoc{responses: make(map[int]reflect.Type)}
+		e.routeDocs[key] = doc
+	}
+	return doc
+}
+
+// Summary documents the route in the OpenAPI document.
+func (r *Route) Summary(summary string) *Route {
+	r.doc().summary = summary
+	return r
+}
+
+// Tags groups the route in the OpenAPI document.
+func (r *Route) Tags(tags ...string) *Route {
+	doc := r.doc()
+	doc.tags = append(doc.tags, tags...)
+	return r
+}
+
+// Request documents the struct the handler binds, such as Request(LoginForm{}).
+// The fields tagged by `uri` and `header` are parameters. The other fields are query parameters
+// for GET, HEAD and DELETE, or the JSON body for the other methods. The fields with a
+// `binding:"required"` tag are required.
+func (r *Route) Request(obj interface{}) *Route {
+	r.doc().request = reflect.TypeOf(obj)
+	return r
+}
+
+// Response documents a JSON response of the route, obj is nil for a response without body.
+func (r *Route) Response(code int, obj interface{}) *Route {
+	r.doc().responses[code] = reflect.TypeOf(obj)
+	return r
+}
+
+// OpenAPI returns the OpenAPI 3 document of the routes. The :param and *catchAll segments
+// of the paths are path parameters, and the named routes have their name as operationId.
+func (e *Engine) OpenAPI(info OpenAPIInfo) *OpenAPIDocument {
+	g := &openAPIGenerator{
+		schemas: make(map[string]*OpenAPISchema),
+		types:   make(map[reflect.Type]string),
+	}
+	doc := &OpenAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    info,
+		Paths:   make(map[string]map[string]*OpenAPIOperation),
+	}
+
+	for _, route := range e.Routes() {
+		apiPath, params := openAPIPath(route.Path)
+		op := &OpenAPIOperation{
+			OperationID: route.Name,
+			Parameters:  params,
+			Responses:   make(map[string]*OpenAPIResponse),
+		}
+
+		rdoc := e.routeDocs[route.Method+" "+route.Path]
+		if rdoc != nil {
+			op.Summary = rdoc.summary
+			op.Tags = rdoc.tags
+			if rdoc.request != nil {
+				g.request(op, route.Method, rdoc.request)
+			}
+			for code, typ := range rdoc.responses {
+				resp := &OpenAPIResponse{Description: http.StatusText(code)}
+				if typ != nil {
+					resp.Content = map[string]OpenAPIMediaType{MIMEJSON: {Schema: g.schema(typ)}}
+				}
+				op.Responses[strconv.Itoa(code)] = resp
+			}
+		}
+		if len(op.Responses) == 0 {
+			op.Responses["200"] = &OpenAPIResponse{Description: http.StatusText(200)}
+		}
+
+		if doc.Paths[apiPath] == nil {
+			doc.Paths[apiPath] = make(map[string]*OpenAPIOperation)
+		}
+		doc.Paths[apiPath][strings.ToLower(route.Method)] = op
+	}
+
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+// Returns a handler serving the OpenAPI document of the engine as JSON:
+//
+//	router.GET("/openapi.json", router.OpenAPIHandler(gin.OpenAPIInfo{Title: "API", Version: "1.0"}))
+func (e *Engine) OpenAPIHandler(info OpenAPIInfo) HandlerFunc {
+	return func(c *Context) {
+		c.JSON(200, e.OpenAPI(info))
+	}
+}
+
+// The openAPIPath converts the :param and *catchAll segments of path into {param}.
+func openAPIPath(routePath string) (string, []*OpenAPIParameter) {
+	var params []*OpenAPIParameter
+	segments := strings.Split(routePath, "/")
+	for i, segment := range segments {
+		if len(segment) > 1 && (segment[0] == ':' || segment[0] == '*') {
+			name := segment[1:]
+			segments[i] = "{" + name + "}"
+			params = append(params, &OpenAPIParameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &OpenAPISchema{Type: "string"},
+			})
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+type openAPIGenerator struct {
+	schemas map[string]*OpenAPISchema
+	types   map[reflect.Type]string
+}
+
+var fileHeaderPtrType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+func (g *openAPIGenerator) request(op *OpenAPIOperation, method string, typ reflect.Type) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ.Kind() != reflect.Struct {
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content:  map[string]OpenAPIMediaType{MIMEJSON: {Schema: g.schema(typ)}},
+		}
+		return
+	}
+
+	inQuery := method == "GET" || method == "HEAD" || method == "DELETE"
+	body := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+	g.eachField(typ, func(field reflect.StructField) {
+		required := isRequiredField(field)
+		if name := tagName(field, "uri"); name != "" {
+			for _, param := range op.Parameters {
+				if param.In == "path" && param.Name == name {
+					param.Schema = g.schema(field.Type)
+				}
+			}
+		} else if name := tagName(field, "header"); name != "" {
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name: name, In: "header", Required: required, Schema: g.schema(field.Type),
+			})
+		} else if inQuery {
+			name := tagName(field, "form")
+			if name == "" {
+				name = field.Name
+			}
+			op.Parameters = append(op.Parameters, &OpenAPIParameter{
+				Name: name, In: "query", Required: required, Schema: g.schema(field.Type),
+			})
+		} else {
+			name := jsonName(field)
+			body.Properties[name] = g.schema(field.Type)
+			if required {
+				body.Required = append(body.Required, name)
+			}
+		}
+	})
+
+	if len(body.Properties) > 0 {
+		op.RequestBody = &OpenAPIRequestBody{
+			Required: true,
+			Content:  map[string]OpenAPIMediaType{MIMEJSON: {Schema: body}},
+		}
+	}
+}
+
+// The eachField calls f with the exported fields of typ, the embedded structs are flattened.
+func (g *openAPIGenerator) eachField(typ reflect.Type, f func(reflect.StructField)) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		if field.Tag.Get("json") == "-" {
+			continue
+		}
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.eachField(embedded, f)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		f(field)
+	}
+}
+
+// The schema returns the schema of typ, the named structs are referenced from the components.
+func (g *openAPIGenerator) schema(typ reflect.Type) *OpenAPISchema {
+	if typ == fileHeaderPtrType {
+		return &OpenAPISchema{Type: "string", Format: "binary"}
+	}
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == reflect.TypeOf(time.Time{}) {
+		return &OpenAPISchema{Type: "string", Format: "date-time"}
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &OpenAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &OpenAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &OpenAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &OpenAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &OpenAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &OpenAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return &OpenAPISchema{Type: "string", Format: "byte"}
+		}
+		return &OpenAPISchema{Type: "array", Items: g.schema(typ.Elem())}
+	case reflect.Map:
+		return &OpenAPISchema{Type: "object", AdditionalProperties: g.schema(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return g.structSchema(typ)
+		}
+		return &OpenAPISchema{Ref: "#/components/schemas/" + g.component(typ)}
+	}
+	return &OpenAPISchema{}
+}
+
+// The component registers the schema of the named struct typ and returns its name.
+func (g *openAPIGenerator) component(typ reflect.Type) string {
+	if name, ok := g.types[typ]; ok {
+		return name
+	}
+	name := typ.Name()
+	if _, taken := g.schemas[name]; taken {
+		name = path.Base(typ.PkgPath()) + "." + name
+	}
+	g.types[typ] = name
+	// registered before its fields, so the recursive types end
+	g.schemas[name] = &OpenAPISchema{}
+	*g.schemas[name] = *g.structSchema(typ)
+	return name
+}
+
+func (g *openAPIGenerator) structSchema(typ reflect.Type) *OpenAPISchema {
+	schema := &OpenAPISchema{Type: "object", Properties: make(map[string]*OpenAPISchema)}
+	g.eachField(typ, func(field reflect.StructField) {
+		name := jsonName(field)
+		schema.Properties[name] = g.schema(field.Type)
+		if isRequiredField(field) {
+			schema.Required = append(schema.Required, name)
+		}
+	})
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func tagName(field reflect.StructField, tag string) string {
+	name := strings.Split(field.Tag.Get(tag), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+func jsonName(field reflect.StructField) string {
+	if name := tagName(field, "json"); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+func isRequiredField(field reflect.StructField) bool {
+	for _, rule := range strings.Split(field.Tag.Get("binding"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
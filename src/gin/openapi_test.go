@@ -0,0 +1,87 @@
+package gin
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+type apiAddress struct {
+	Street string `json:"street" binding:"required"`
+}
+
+type apiUser struct {
+	ID        int64             `json:"id"`
+	Name      string            `json:"name" binding:"required,min=1"`
+	Emails    []string          `json:"emails,omitempty"`
+	Address   *apiAddress       `json:"address"`
+	Friends   []apiUser         `json:"friends"`
+	CreatedAt time.Time         `json:"created_at"`
+	Labels    map[string]string `json:"labels"`
+	Secret    string            `json:"-"`
+	internal  int
+}
+
+type apiUserQuery struct {
+	ID    uint64 `uri:"id"`
+	Token string `header:"X-Token" binding:"required"`
+	Full  bool   `form:"full"`
+}
+
+func TestOpenAPI(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", handlerTest1).Name("getUser").
+		Summary("Show a user").Tags("users").
+		Request(apiUserQuery{}).
+		Response(200, apiUser{}).
+		Response(404, nil)
+	router.POST("/users", handlerTest1).Request(&apiUser{}).Response(201, apiUser{})
+	router.GET("/files/*path", handlerTest2)
+	router.GET("/openapi.json", router.OpenAPIHandler(OpenAPIInfo{Title: "test", Version: "1.0"}))
+
+	doc := router.OpenAPI(OpenAPIInfo{Title: "test", Version: "1.0"})
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Equal(t, 4, len(doc.Paths))
+
+	get := doc.Paths["/users/{id}"]["get"]
+	assert.Equal(t, "getUser", get.OperationID)
+	assert.Equal(t, "Show a user", get.Summary)
+	assert.Equal(t, []string{"users"}, get.Tags)
+	assert.Equal(t, []*OpenAPIParameter{
+		{Name: "id", In: "path", Required: true, Schema: &OpenAPISchema{Type: "integer", Format: "int64"}},
+		{Name: "X-Token", In: "header", Required: true, Schema: &OpenAPISchema{Type: "string"}},
+		{Name: "full", In: "query", Schema: &OpenAPISchema{Type: "boolean"}},
+	}, get.Parameters)
+	assert.Nil(t, get.RequestBody)
+	assert.Equal(t, "#/components/schemas/apiUser", get.Responses["200"].Content[MIMEJSON].Schema.Ref)
+	assert.Equal(t, &OpenAPIResponse{Description: "Not Found"}, get.Responses["404"])
+
+	post := doc.Paths["/users"]["post"]
+	body := post.RequestBody.Content[MIMEJSON].Schema
+	assert.Equal(t, []string{"name"}, body.Required)
+	assert.Equal(t, 7, len(body.Properties))
+	assert.Equal(t, "Created", post.Responses["201"].Description)
+
+	files := doc.Paths["/files/{path}"]["get"]
+	assert.Equal(t, "path", files.Parameters[0].Name)
+	assert.Equal(t, "OK", files.Responses["200"].Description)
+
+	user := doc.Components.Schemas["apiUser"]
+	assert.Equal(t, &OpenAPISchema{Type: "array", Items: &OpenAPISchema{Ref: "#/components/schemas/apiUser"}}, user.Properties["friends"])
+	assert.Equal(t, &OpenAPISchema{Type: "string", Format: "date-time"}, user.Properties["created_at"])
+	assert.Equal(t, &OpenAPISchema{Type: "object", AdditionalProperties: &OpenAPISchema{Type: "string"}}, user.Properties["labels"])
+	assert.Nil(t, user.Properties["Secret"])
+	assert.Nil(t, user.Properties["internal"])
+	assert.Equal(t, []string{"street"}, doc.Components.Schemas["apiAddress"].Required)
+
+	w := performRequest(router, "GET", "/openapi.json")
+	assert.Equal(t, 200, w.Code)
+	var served map[string]interface{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(t, "3.0.3", served["openapi"])
+	assert.NotNil(t, served["paths"].(map[string]interface{})["/users/{id}"])
+}
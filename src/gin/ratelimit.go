@@ -0,0 +1,118 @@
+package gin
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type RateLimitConfig struct {
+	// Rate is the number of requests allowed by second, on average.
+	Rate float64
+
+	// Burst is the number of requests allowed at once, 1 when 0.
+	Burst int
+
+	// KeyFunc returns the key limited, c.ClientIP() by default.
+	KeyFunc func(c *Context) string
+}
+
+// Returns a middleware allowing rate requests by second with bursts of burst requests by client IP.
+// See RateLimitWithConfig.
+func RateLimit(rate float64, burst int) HandlerFunc {
+	return RateLimitWithConfig(RateLimitConfig{Rate: rate, Burst: burst})
+}
+
+// Returns a token bucket rate limiting middleware. Each key has a bucket of Burst tokens
+// refilled at Rate tokens by second, and a request takes a token. When the bucket is empty
+// the request is aborted with 429 Too Many Requests and a Retry-After header.
+// The X-RateLimit-Limit and X-RateLimit-Remaining headers are set on the allowed requests.
+func RateLimitWithConfig(conf RateLimitConfig) HandlerFunc {
+	limiter := newRateLimiter(conf)
+	keyFunc := conf.KeyFunc
+	if keyFunc == nil {
+		keyFunc = func(c *Context) string {
+			return c.ClientIP()
+		}
+	}
+	limit := strconv.Itoa(limiter.burst)
+
+	return func(c *Context) {
+		remaining, retryAfter, ok := limiter.take(keyFunc(c))
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.AbortWithStatus(429)
+			return
+		}
+		c.Header("X-RateLimit-Limit", limit)
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	}
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type rateLimiter struct {
+	sync.Mutex
+	rate      float64
+	burst     int
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func newRateLimiter(conf RateLimitConfig) *rateLimiter {
+	if conf.Rate <= 0 {
+		panic("the rate must be positive")
+	}
+	if conf.Burst <= 0 {
+		conf.Burst = 1
+	}
+	return &rateLimiter{
+		rate:    conf.Rate,
+		burst:   conf.Burst,
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// The take returns the tokens left after taking one, or how long to wait for a token.
+func (l *rateLimiter) take(key string) (int, time.Duration, bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return 0, wait, false
+	}
+	b.tokens--
+	return int(b.tokens), 0, true
+}
+
+// The sweep drops the buckets that are full again, as often as a bucket takes to fill up.
+func (l *rateLimiter) sweep(now time.Time) {
+	fill := time.Duration(float64(l.burst) / l.rate * float64(time.Second))
+	if now.Sub(l.lastSweep) < fill {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= fill {
+			delete(l.buckets, key)
+		}
+	}
+}
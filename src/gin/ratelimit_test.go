@@ -0,0 +1,72 @@
+package gin
+
+import (
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	router := New()
+	router.Use(RateLimitWithConfig(RateLimitConfig{
+		Rate:  1,
+		Burst: 2,
+		KeyFunc: func(c *Context) string {
+			return c.Request.URL.Query().Get("key")
+		},
+	}))
+	router.GET("/", func(c *Context) {
+		c.String(200, "ok")
+	})
+
+	w := performRequest(router, "GET", "/?key=a")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = performRequest(router, "GET", "/?key=a")
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = performRequest(router, "GET", "/?key=a")
+	assert.Equal(t, 429, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	w = performRequest(router, "GET", "/?key=b")
+	assert.Equal(t, 200, w.Code)
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	now := time.Unix(0, 0)
+	limiter := newRateLimiter(RateLimitConfig{Rate: 2, Burst: 1})
+	limiter.now = func() time.Time {
+		return now
+	}
+
+	_, _, ok := limiter.take("a")
+	assert.True(t, ok)
+	_, wait, ok := limiter.take("a")
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	now = now.Add(250 * time.Millisecond)
+	_, wait, ok = limiter.take("a")
+	assert.False(t, ok)
+	assert.Equal(t, 250*time.Millisecond, wait)
+
+	now = now.Add(250 * time.Millisecond)
+	_, _, ok = limiter.take("a")
+	assert.True(t, ok)
+
+	// the bucket of a is full again after 500ms, it is dropped by the next sweep
+	now = now.Add(time.Second)
+	limiter.take("b")
+	assert.Len(t, limiter.buckets, 1)
+
+	assert.Panics(t, func() {
+		RateLimit(0, 1)
+	})
+}
@@ -0,0 +1,54 @@
+package gin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+const (
+	// The key of the request ID in the context, see RequestID.
+	RequestIDKey = "requestID"
+
+	RequestIDHeader = "X-Request-ID"
+)
+
+// Returns a middleware giving each request an ID, see RequestIDWithGenerator.
+// The IDs are 32 random hexadecimal characters.
+func RequestID() HandlerFunc {
+	return RequestIDWithGenerator(newRequestID)
+}
+
+// Returns a middleware giving each request an ID: the X-Request-ID header of the request
+// when it is valid, else a new one from generate. The ID is set to the key RequestIDKey
+// in this context, and to the X-Request-ID header of the response and of the request, so
+// it is logged by the Logger and propagated by the handlers forwarding the request.
+func RequestIDWithGenerator(generate func() string) HandlerFunc {
+	return func(c *Context) {
+		id := c.Request.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = generate()
+			c.Request.Header.Set(RequestIDHeader, id)
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+	}
+}
+
+// The validRequestID accepts up to 128 printable ASCII characters.
+func validRequestID(id string) bool {
+	if len(id) == 0 || len(id) > 128 {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,38 @@
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	var ids []string
+	router := New()
+	router.Use(RequestID())
+	router.GET("/", func(c *Context) {
+		id, _ := c.Get(RequestIDKey)
+		assert.Equal(t, id, c.Request.Header.Get(RequestIDHeader))
+		ids = append(ids, id.(string))
+	})
+
+	w := performRequest(router, "GET", "/")
+	assert.Len(t, ids[0], 32)
+	assert.Equal(t, ids[0], w.Header().Get(RequestIDHeader))
+
+	performRequest(router, "GET", "/")
+	assert.NotEqual(t, ids[0], ids[1])
+
+	for id, valid := range map[string]bool{"abc-123": true, "has space": false, strings.Repeat("x", 129): false} {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set(RequestIDHeader, id)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, valid, w.Header().Get(RequestIDHeader) == id, id)
+	}
+}
@@ -39,49 +39,54 @@ func (group *RouterGroup) Group(relativePath string, handlers ...HandlerFunc) *R
 // This function is intended for bulk loading and to allow the usage of less
 // frequently used, non-standardized or custom methods (e.g. for internal
 // communication with a proxy).
-func (group *RouterGroup) handle(httpMethod, relativePath string, handlers HandlersChain) {
+//
+// The returned Route can be named, so its url can be generated by Engine.URL:
+//
+//	router.GET("/user/:id", showUser).Name("user")
+func (group *RouterGroup) handle(httpMethod, relativePath string, handlers HandlersChain) *Route {
 	absolutePath := group.calculateAbsolutePath(relativePath)
 	handlers = group.combineHandlers(handlers)
 	group.engine.addRoute(httpMethod, absolutePath, handlers)
+	return &Route{Method: httpMethod, Path: absolutePath, engine: group.engine}
 }
 
-func (group *RouterGroup) Handle(httpMethod, relativePath string, handlers ...HandlerFunc) {
-	group.handle(httpMethod, relativePath, handlers)
+func (group *RouterGroup) Handle(httpMethod, relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle(httpMethod, relativePath, handlers)
 }
 
 // POST is a shortcut for router.Handle("POST", path, handle)
-func (group *RouterGroup) POST(relativePath string, handlers ...HandlerFunc) {
-	group.handle("POST", relativePath, handlers)
+func (group *RouterGroup) POST(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("POST", relativePath, handlers)
 }
 
 // GET is a shortcut for router.Handle("GET", path, handle)
-func (group *RouterGroup) GET(relativePath string, handlers ...HandlerFunc) {
-	group.handle("GET", relativePath, handlers)
+func (group *RouterGroup) GET(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("GET", relativePath, handlers)
 }
 
 // DELETE is a shortcut for router.Handle("DELETE", path, handle)
-func (group *RouterGroup) DELETE(relativePath string, handlers ...HandlerFunc) {
-	group.handle("DELETE", relativePath, handlers)
+func (group *RouterGroup) DELETE(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("DELETE", relativePath, handlers)
 }
 
 // PATCH is a shortcut for router.Handle("PATCH", path, handle)
-func (group *RouterGroup) PATCH(relativePath string, handlers ...HandlerFunc) {
-	group.handle("PATCH", relativePath, handlers)
+func (group *RouterGroup) PATCH(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("PATCH", relativePath, handlers)
 }
 
 // PUT is a shortcut for router.Handle("PUT", path, handle)
-func (group *RouterGroup) PUT(relativePath string, handlers ...HandlerFunc) {
-	group.handle("PUT", relativePath, handlers)
+func (group *RouterGroup) PUT(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("PUT", relativePath, handlers)
 }
 
 // OPTIONS is a shortcut for router.Handle("OPTIONS", path, handle)
-func (group *RouterGroup) OPTIONS(relativePath string, handlers ...HandlerFunc) {
-	group.handle("OPTIONS", relativePath, handlers)
+func (group *RouterGroup) OPTIONS(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("OPTIONS", relativePath, handlers)
 }
 
 // HEAD is a shortcut for router.Handle("HEAD", path, handle)
-func (group *RouterGroup) HEAD(relativePath string, handlers ...HandlerFunc) {
-	group.handle("HEAD", relativePath, handlers)
+func (group *RouterGroup) HEAD(relativePath string, handlers ...HandlerFunc) *Route {
+	return group.handle("HEAD", relativePath, handlers)
 }
 
 func (group *RouterGroup) Any(relativePath string, handlers ...HandlerFunc) {
@@ -0,0 +1,110 @@
+package gin
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// RouteInfo describes a registered route, as returned by Engine.Routes.
+type RouteInfo struct {
+	Method  string
+	Path    string
+	Handler string
+	Name    string
+}
+
+type RoutesInfo []RouteInfo
+
+// Route is returned when a route is registered, so it can be named and documented for OpenAPI.
+type Route struct {
+	Method string
+	Path   string
+	engine *Engine
+}
+
+// Name names the route for Engine.URL. It panics if the name is already used by another route.
+// A route has one name, naming it again replaces its previous name.
+func (r *Route) Name(name string) *Route {
+	e := r.engine
+	if e.namedRoutes == nil {
+		e.namedRoutes = make(map[string]*Route)
+	}
+	if other, ok := e.namedRoutes[name]; ok && (other.Method != r.Method || other.Path != r.Path) {
+		panic("route name '" + name + "' is already used by " + other.Method + " " + other.Path)
+	}
+	doc := r.doc()
+	if doc.name != "" && doc.name != name {
+		delete(e.namedRoutes, doc.name)
+	}
+	doc.name = name
+	e.namedRoutes[name] = r
+	return r
+}
+
+// Routes returns the registered routes with the name of their last handler.
+func (e *Engine) Routes() (routes RoutesInfo) {
+	for _, tree := range e.trees {
+		routes = iterateRoutes(tree.method, "", routes, tree.root)
+	}
+	for i := range routes {
+		routes[i].Name = e.routeName(routes[i].Method, routes[i].Path)
+	}
+	return routes
+}
+
+func iterateRoutes(method, path string, routes RoutesInfo, root *Node) RoutesInfo {
+	path += root.path
+	if len(root.handlers) > 0 {
+		handler := root.handlers[len(root.handlers)-1]
+		routes = append(routes, RouteInfo{
+			Method:  method,
+			Path:    path,
+			Handler: nameOfFunction(handler),
+		})
+	}
+	for _, child := range root.children {
+		routes = iterateRoutes(method, path, routes, child)
+	}
+	return routes
+}
+
+func (e *Engine) routeName(method, path string) string {
+	if doc, ok := e.routeDocs[method+" "+path]; ok {
+		return doc.name
+	}
+	return ""
+}
+
+// URL generates the path of the named route, filling its :param and *catchAll segments
+// with params in order. The values are escaped, but the slashes of a catchAll value are kept.
+//
+//	router.GET("/user/:id/*action", h).Name("user")
+//	router.URL("user", "7", "edit/profile") // "/user/7/edit/profile"
+func (e *Engine) URL(name string, params ...string) (string, error) {
+	r, ok := e.namedRoutes[name]
+	if !ok {
+		return "", errors.New("gin: unknown route name " + name)
+	}
+
+	segments := strings.Split(r.Path, "/")
+	n := 0
+	for i, segment := range segments {
+		if len(segment) == 0 || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+		if n == len(params) {
+			return "", errors.New("gin: missing parameter " + segment + " for route " + name)
+		}
+		if segment[0] == ':' {
+			segments[i] = url.PathEscape(params[n])
+		} else {
+			segments[i] = (&url.URL{Path: strings.TrimPrefix(params[n], "/")}).EscapedPath()
+		}
+		n++
+	}
+	if n != len(params) {
+		return "", errors.New("gin: too many parameters for route " + name)
+	}
+	return strings.Join(segments, "/"), nil
+}
@@ -185,3 +185,71 @@ func TestRouteStaticFile(t *testing.T) {
 	assert.Equal(t, w3, w4)
 	assert.Equal(t, w3.Code, 200)
 }
+
+func handlerTest1(c *Context) {}
+func handlerTest2(c *Context) {}
+
+func TestRoutesInfo(t *testing.T) {
+	router := New()
+	router.GET("/users", handlerTest1)
+	router.GET("/users/:id", handlerTest1).Name("user")
+	router.Group("/files").POST("/*path", handlerTest2)
+
+	routes := router.Routes()
+	assert.Len(t, routes, 3)
+	assert.Contains(t, routes, RouteInfo{Method: "GET", Path: "/users", Handler: "gin.handlerTest1"})
+	assert.Contains(t, routes, RouteInfo{Method: "GET", Path: "/users/:id", Handler: "gin.handlerTest1", Name: "user"})
+	assert.Contains(t, routes, RouteInfo{Method: "POST", Path: "/files/*path", Handler: "gin.handlerTest2"})
+}
+
+func TestRouteURL(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", handlerTest1).Name("user")
+	router.GET("/static/*filepath", handlerTest1).Name("static")
+	router.Handle("GET", "/about", handlerTest1).Name("about")
+
+	u, err := router.URL("user", "john smith")
+	assert.NoError(t, err)
+	assert.Equal(t, u, "/users/john%20smith")
+
+	u, err = router.URL("static", "/css/main file.css")
+	assert.NoError(t, err)
+	assert.Equal(t, u, "/static/css/main%20file.css")
+
+	u, err = router.URL("about")
+	assert.NoError(t, err)
+	assert.Equal(t, u, "/about")
+
+	_, err = router.URL("user")
+	assert.Error(t, err)
+	_, err = router.URL("user", "1", "2")
+	assert.Error(t, err)
+	_, err = router.URL("unknown")
+	assert.Error(t, err)
+
+	assert.Panics(t, func() {
+		router.GET("/other", handlerTest1).Name("user")
+	})
+	assert.Panics(t, func() {
+		router.POST("/users/:id", handlerTest1).Name("user")
+	})
+}
+
+func TestRouteRename(t *testing.T) {
+	router := New()
+	router.GET("/users/:id", handlerTest1).Name("a").Name("b")
+
+	routes := router.Routes()
+	assert.Equal(t, "b", routes[0].Name)
+	_, err := router.URL("a")
+	assert.Error(t, err)
+	u, err := router.URL("b", "7")
+	assert.NoError(t, err)
+	assert.Equal(t, "/users/7", u)
+
+	// the previous name is free again
+	router.GET("/other", handlerTest1).Name("a")
+	u, err = router.URL("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "/other", u)
+}
@@ -0,0 +1,232 @@
+package gin
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Server serves an Engine on several listeners and shuts them down gracefully.
+// Unlike Engine.Run, it can be stopped: in-flight requests are drained before it returns.
+//
+//	srv := gin.NewServer(router)
+//	srv.Listen(":8080")
+//	srv.ListenTLS(":8443", "cert.pem", "key.pem")
+//	srv.AddListener(netutil.LimitListener(l, 100))
+//	srv.OnShutdown(db.Close)
+//	err := srv.Run() // blocks until SIGINT or SIGTERM
+type Server struct {
+	// HTTPServer serves the engine on every listener, its timeouts can be set before Start.
+	HTTPServer *http.Server
+
+	// DrainTimeout bounds how long Shutdown waits for the in-flight requests before their
+	// connections are closed, 0 waits until they are done.
+	DrainTimeout time.Duration
+
+	mu           sync.Mutex
+	listeners    []net.Listener
+	onStartup    []func() error
+	onShutdown   []func() error
+	started      bool
+	errc         chan error
+	done         chan struct{}
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// Returns a Server for engine with a drain timeout of 10 seconds and no listener.
+func NewServer(engine *Engine) *Server {
+	return &Server{
+		HTTPServer:   &http.Server{Handler: engine},
+		DrainTimeout: 10 * time.Second,
+		errc:         make(chan error, 1),
+		done:         make(chan struct{}),
+	}
+}
+
+// Listen adds a TCP listener on addr.
+func (s *Server) Listen(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.AddListener(l)
+	return nil
+}
+
+// ListenTLS adds a HTTPS listener on addr with the certificate and key files.
+func (s *Server) ListenTLS(addr, certFile, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	s.AddListener(tls.NewListener(l, &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"http/1.1"},
+	}))
+	return nil
+}
+
+// ListenUnix adds a listener on the unix socket file. A socket left by a previous run is removed
+// first, while any other file at that path fails the listen.
+func (s *Server) ListenUnix(file string) error {
+	if info, err := os.Lstat(file); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(file)
+	}
+	l, err := net.Listen("unix", file)
+	if err != nil {
+		return err
+	}
+	s.AddListener(l)
+	return nil
+}
+
+// AddListener adds a listener created by the caller, such as a netutil.LimitListener.
+// A listener added after Start is served immediately.
+func (s *Server) AddListener(l net.Listener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, l)
+	if s.started {
+		go s.serve(l)
+	}
+}
+
+// Addrs returns the addresses of the listeners.
+func (s *Server) Addrs() []net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addrs := make([]net.Addr, len(s.listeners))
+	for i, l := range s.listeners {
+		addrs[i] = l.Addr()
+	}
+	return addrs
+}
+
+// OnStartup adds a hook called once every listener is being served.
+// If a hook fails the server is shut down and Start returns its error.
+func (s *Server) OnStartup(hook func() error) {
+	s.mu.Lock()
+	s.onStartup = append(s.onStartup, hook)
+	s.mu.Unlock()
+}
+
+// OnShutdown adds a hook called after the in-flight requests are drained, to release resources.
+func (s *Server) OnShutdown(hook func() error) {
+	s.mu.Lock()
+	s.onShutdown = append(s.onShutdown, hook)
+	s.mu.Unlock()
+}
+
+// Start serves every listener in the background and calls the startup hooks.
+func (s *Server) Start() error {
+	s.mu.Lock()
+	if s.started {
+		s.mu.Unlock()
+		return errors.New("gin: server already started")
+	}
+	if len(s.listeners) == 0 {
+		s.mu.Unlock()
+		return errors.New("gin: server has no listener")
+	}
+	s.started = true
+	for _, l := range s.listeners {
+		go s.serve(l)
+	}
+	onStartup := s.onStartup
+	s.mu.Unlock()
+
+	for _, hook := range onStartup {
+		if err := hook(); err != nil {
+			s.Shutdown()
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) serve(l net.Listener) {
+	debugPrint("Listening and serving HTTP on %s\n", l.Addr())
+	if err := s.HTTPServer.Serve(l); err != nil && err != http.ErrServerClosed {
+		select {
+		case s.errc <- err:
+		default:
+		}
+	}
+}
+
+// Run starts the server and blocks until SIGINT or SIGTERM is received, a listener fails
+// or Shutdown is called, then shuts the server down gracefully.
+func (s *Server) Run() (err error) {
+	defer func() {
+		debugPrintError(err)
+	}()
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sig)
+
+	if err = s.Start(); err != nil {
+		return
+	}
+
+	select {
+	case err = <-s.errc:
+		s.Shutdown()
+		return
+	case <-sig:
+		debugPrint("Shutting down, draining the in-flight requests\n")
+	case <-s.done:
+	}
+	err = s.Shutdown()
+	return
+}
+
+// Shutdown stops accepting connections, waits up to DrainTimeout for the in-flight requests
+// and calls the shutdown hooks. It is safe to call several times, every call returns the same error.
+func (s *Server) Shutdown() error {
+	s.shutdownOnce.Do(func() {
+		s.mu.Lock()
+		started := s.started
+		listeners := s.listeners
+		onShutdown := s.onShutdown
+		s.mu.Unlock()
+
+		ctx := context.Background()
+		if s.DrainTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, s.DrainTimeout)
+			defer cancel()
+		}
+
+		err := s.HTTPServer.Shutdown(ctx)
+		if err != nil {
+			s.HTTPServer.Close()
+		}
+		if !started {
+			for _, l := range listeners {
+				l.Close()
+			}
+		}
+
+		for _, hook := range onShutdown {
+			if hookErr := hook(); hookErr != nil && err == nil {
+				err = hookErr
+			}
+		}
+		s.shutdownErr = err
+		close(s.done)
+	})
+	return s.shutdownErr
+}
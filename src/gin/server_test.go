@@ -0,0 +1,161 @@
+package gin
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/netutil"
+)
+
+func TestServerGracefulShutdown(t *testing.T) {
+	started := make(chan bool)
+	release := make(chan bool)
+	router := New()
+	router.GET("/slow", func(c *Context) {
+		started <- true
+		<-release
+		c.String(200, "done")
+	})
+
+	srv := NewServer(router)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	srv.AddListener(netutil.LimitListener(l, 10))
+
+	var calls []string
+	ready := make(chan bool, 1)
+	srv.OnStartup(func() error {
+		calls = append(calls, "startup")
+		ready <- true
+		return nil
+	})
+	srv.OnShutdown(func() error {
+		calls = append(calls, "shutdown")
+		return nil
+	})
+
+	runErr := make(chan error)
+	go func() {
+		runErr <- srv.Run()
+	}()
+	<-ready
+
+	body := make(chan string)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		body <- string(b)
+	}()
+	<-started
+
+	shutdownErr := make(chan error)
+	go func() {
+		shutdownErr <- srv.Shutdown()
+	}()
+	time.Sleep(50 * time.Millisecond)
+	_, err = net.Dial("tcp", l.Addr().String())
+	assert.Error(t, err)
+
+	release <- true
+	assert.Equal(t, "done", <-body)
+	assert.NoError(t, <-shutdownErr)
+	assert.NoError(t, <-runErr)
+	assert.Equal(t, []string{"startup", "shutdown"}, calls)
+}
+
+func TestServerDrainTimeout(t *testing.T) {
+	router := New()
+	router.GET("/hang", func(c *Context) {
+		<-c.Done()
+	})
+
+	srv := NewServer(router)
+	srv.DrainTimeout = 50 * time.Millisecond
+	assert.NoError(t, srv.Listen("127.0.0.1:0"))
+	assert.NoError(t, srv.Start())
+	assert.Error(t, srv.Start())
+
+	go http.Get("http://" + srv.Addrs()[0].String() + "/hang")
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, context.DeadlineExceeded, srv.Shutdown())
+	assert.Equal(t, context.DeadlineExceeded, srv.Shutdown())
+}
+
+func TestServerUnixAndErrors(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gin")
+	defer os.RemoveAll(dir)
+
+	router := New()
+	router.GET("/", func(c *Context) {
+		c.String(200, "unix")
+	})
+
+	srv := NewServer(router)
+	assert.Error(t, srv.Start())
+	assert.Error(t, srv.ListenTLS("127.0.0.1:0", "missing.pem", "missing.key"))
+
+	file := filepath.Join(dir, "gin.sock")
+	assert.NoError(t, srv.ListenUnix(file))
+	assert.NoError(t, srv.Start())
+
+	client := &http.Client{Transport: &http.Transport{
+		Dial: func(network, addr string) (net.Conn, error) {
+			return net.Dial("unix", file)
+		},
+	}}
+	resp, err := client.Get("http://unix/")
+	if assert.NoError(t, err) {
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.Equal(t, "unix", string(b))
+	}
+	assert.NoError(t, srv.Shutdown())
+}
+
+func TestServerListenUnixStale(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "gin")
+	defer os.RemoveAll(dir)
+
+	// a regular file is never removed
+	file := filepath.Join(dir, "data")
+	ioutil.WriteFile(file, []byte("data"), 0644)
+	srv := NewServer(New())
+	assert.Error(t, srv.ListenUnix(file))
+	b, _ := ioutil.ReadFile(file)
+	assert.Equal(t, "data", string(b))
+
+	// the socket left by a previous run is replaced
+	sock := filepath.Join(dir, "gin.sock")
+	l, err := net.ListenUnix("unix", &net.UnixAddr{Name: sock, Net: "unix"})
+	if assert.NoError(t, err) {
+		l.SetUnlinkOnClose(false)
+		l.Close()
+	}
+	assert.NoError(t, srv.ListenUnix(sock))
+
+	// hooks may be added while the server starts
+	done := make(chan struct{})
+	go func() {
+		srv.OnStartup(func() error { return nil })
+		srv.OnShutdown(func() error { return nil })
+		close(done)
+	}()
+	assert.NoError(t, srv.Start())
+	<-done
+	assert.NoError(t, srv.Shutdown())
+}
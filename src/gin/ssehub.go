@@ -0,0 +1,227 @@
+package gin
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/manucorporat/sse"
+)
+
+// SSEHub broadcasts server-sent events to the clients subscribed to a topic.
+// It keeps the last events of each topic, so a client reconnecting with a Last-Event-ID
+// receives the events it missed. A client that does not read its events fast enough to
+// keep its buffer from filling up is evicted, its stream ends and the browser reconnects.
+//
+//	hub := gin.NewSSEHub()
+//	router.GET("/stream/:room", func(c *gin.Context) {
+//		hub.Stream(c, c.Param("room"))
+//	})
+//	hub.Publish("lobby", sse.Event{Event: "message", Data: "hello"})
+type SSEHub struct {
+	// BufferSize is the number of events a client can lag behind before it is evicted.
+	BufferSize int
+
+	// History is the number of events kept by topic for the reconnecting clients.
+	History int
+
+	// KeepAlive is the interval of the comments sent to idle clients, 0 disables them.
+	KeepAlive time.Duration
+
+	mu     sync.Mutex
+	topics map[string]*sseTopic
+}
+
+type sseTopic struct {
+	clients map[*SSEClient]bool
+	history []sse.Event
+	next    int
+	seq     uint64
+}
+
+// SSEClient is a subscription to a topic of a SSEHub.
+type SSEClient struct {
+	Topic   string
+	hub     *SSEHub
+	events  chan sse.Event
+	evicted bool
+}
+
+// Returns a SSEHub with a buffer of 16 events by client, a history of 100 events by topic
+// and a keepalive every 15 seconds.
+func NewSSEHub() *SSEHub {
+	return &SSEHub{
+		BufferSize: 16,
+		History:    100,
+		KeepAlive:  15 * time.Second,
+		topics:     make(map[string]*sseTopic),
+	}
+}
+
+func (h *SSEHub) topic(name string) *sseTopic {
+	t, ok := h.topics[name]
+	if !ok {
+		t = &sseTopic{clients: make(map[*SSEClient]bool)}
+		h.topics[name] = t
+	}
+	return t
+}
+
+// Publish sends event to the clients of topic and returns it. An event without Id
+// is given the next sequence number of the topic.
+func (h *SSEHub) Publish(topic string, event sse.Event) sse.Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t := h.topic(topic)
+	t.seq++
+	if event.Id == "" {
+		event.Id = strconv.FormatUint(t.seq, 10)
+	}
+	if h.History > 0 {
+		if len(t.history) < h.History {
+			t.history = append(t.history, event)
+		} else {
+			t.history[t.next] = event
+			t.next = (t.next + 1) % len(t.history)
+		}
+	}
+
+	for client := range t.clients {
+		select {
+		case client.events <- event:
+		default:
+			h.evict(t, client)
+		}
+	}
+	return event
+}
+
+// The missed returns the events of the history after lastEventID in order, or all of
+// them when lastEventID is not in the history anymore.
+func (t *sseTopic) missed(lastEventID string) []sse.Event {
+	events := make([]sse.Event, 0, len(t.history))
+	events = append(events, t.history[t.next:]...)
+	events = append(events, t.history[:t.next]...)
+	for i, event := range events {
+		if event.Id == lastEventID {
+			return events[i+1:]
+		}
+	}
+	return events
+}
+
+// Subscribe adds a client to topic. When lastEventID is not empty, the events published
+// after it are queued first.
+func (h *SSEHub) Subscribe(topic, lastEventID string) *SSEClient {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t := h.topic(topic)
+	var missed []sse.Event
+	if lastEventID != "" {
+		missed = t.missed(lastEventID)
+	}
+
+	client := &SSEClient{
+		Topic:  topic,
+		hub:    h,
+		events: make(chan sse.Event, h.BufferSize+len(missed)),
+	}
+	for _, event := range missed {
+		client.events <- event
+	}
+	t.clients[client] = true
+	return client
+}
+
+// Unsubscribe removes the client from its topic and closes its events.
+func (h *SSEHub) Unsubscribe(client *SSEClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if t, ok := h.topics[client.Topic]; ok && t.clients[client] {
+		delete(t.clients, client)
+		close(client.events)
+	}
+}
+
+func (h *SSEHub) evict(t *sseTopic, client *SSEClient) {
+	debugPrint("[WARNING] SSE client of topic %s evicted, its buffer is full\n", client.Topic)
+	client.evicted = true
+	delete(t.clients, client)
+	close(client.events)
+}
+
+// Close removes topic with its history and ends the streams of its clients.
+func (h *SSEHub) Close(topic string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if t, ok := h.topics[topic]; ok {
+		for client := range t.clients {
+			close(client.events)
+		}
+		delete(h.topics, topic)
+	}
+}
+
+// Clients returns the number of clients subscribed to topic.
+func (h *SSEHub) Clients(topic string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if t, ok := h.topics[topic]; ok {
+		return len(t.clients)
+	}
+	return 0
+}
+
+// Events returns the events of the client, closed when it is unsubscribed or evicted.
+func (client *SSEClient) Events() <-chan sse.Event {
+	return client.events
+}
+
+// Evicted reports whether the client was evicted for being too slow, so its events are closed.
+func (client *SSEClient) Evicted() bool {
+	client.hub.mu.Lock()
+	defer client.hub.mu.Unlock()
+	return client.evicted
+}
+
+// Stream subscribes the request to topic, resuming after its Last-Event-ID header,
+// and writes the events until the client goes away or its subscription ends.
+func (h *SSEHub) Stream(c *Context, topic string) {
+	client := h.Subscribe(topic, c.Request.Header.Get("Last-Event-ID"))
+	defer h.Unsubscribe(client)
+
+	var keepalive <-chan time.Time
+	if h.KeepAlive > 0 {
+		ticker := time.NewTicker(h.KeepAlive)
+		defer ticker.Stop()
+		keepalive = ticker.C
+	}
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", sse.ContentType)
+	header.Set("Cache-Control", "no-cache")
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	for {
+		select {
+		case event, ok := <-client.Events():
+			if !ok {
+				return
+			}
+			c.Render(-1, event)
+		case <-keepalive:
+			c.Writer.WriteString(": keepalive\n\n")
+		case <-c.Done():
+			return
+		}
+		c.Writer.Flush()
+	}
+}
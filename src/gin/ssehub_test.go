@@ -0,0 +1,102 @@
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/manucorporat/sse"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSEHubReplay(t *testing.T) {
+	hub := NewSSEHub()
+	hub.History = 3
+	for _, data := range []string{"a", "b", "c", "d"} {
+		hub.Publish("room", sse.Event{Data: data})
+	}
+
+	client := hub.Subscribe("room", "2")
+	assert.Equal(t, sse.Event{Id: "3", Data: "c"}, <-client.Events())
+	assert.Equal(t, sse.Event{Id: "4", Data: "d"}, <-client.Events())
+
+	// the id 1 is not in the history anymore, the whole history is replayed
+	old := hub.Subscribe("room", "1")
+	assert.Len(t, old.Events(), 3)
+	assert.Equal(t, 2, hub.Clients("room"))
+
+	hub.Publish("room", sse.Event{Event: "chat", Data: "e"})
+	assert.Equal(t, sse.Event{Id: "5", Event: "chat", Data: "e"}, <-client.Events())
+
+	hub.Unsubscribe(client)
+	_, ok := <-client.Events()
+	assert.False(t, ok)
+	assert.Equal(t, 1, hub.Clients("room"))
+
+	hub.Close("room")
+	assert.Equal(t, 0, hub.Clients("room"))
+	assert.Len(t, hub.Subscribe("room", "1").Events(), 0)
+}
+
+func TestSSEHubEvictsSlowClient(t *testing.T) {
+	hub := NewSSEHub()
+	hub.BufferSize = 2
+	slow := hub.Subscribe("room", "")
+	fast := hub.Subscribe("room", "")
+
+	for i := 0; i < 3; i++ {
+		hub.Publish("room", sse.Event{Data: i})
+		<-fast.Events()
+	}
+
+	assert.Len(t, slow.Events(), 2)
+	<-slow.Events()
+	<-slow.Events()
+	_, ok := <-slow.Events()
+	assert.False(t, ok)
+	assert.True(t, slow.Evicted())
+	assert.False(t, fast.Evicted())
+	assert.Equal(t, 1, hub.Clients("room"))
+}
+
+func TestSSEHubStream(t *testing.T) {
+	hub := NewSSEHub()
+	hub.KeepAlive = 10 * time.Millisecond
+	router := New()
+	router.GET("/stream/:room", func(c *Context) {
+		hub.Stream(c, c.Param("room"))
+	})
+	ts := httptest.NewServer(router)
+	defer ts.Close()
+
+	hub.Publish("lobby", sse.Event{Event: "message", Data: "missed"})
+	hub.Publish("lobby", sse.Event{Event: "message", Data: "old"})
+
+	req, _ := http.NewRequest("GET", ts.URL+"/stream/lobby", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, sse.ContentType, resp.Header.Get("Content-Type"))
+
+	d := sse.NewDecoder(resp.Body)
+	event, err := d.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, sse.Event{Id: "2", Event: "message", Data: "old"}, event)
+
+	// wait for a keepalive before the next event
+	time.Sleep(30 * time.Millisecond)
+	hub.Publish("lobby", sse.Event{Event: "message", Data: map[string]string{"user": "gin"}})
+	event, err = d.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, sse.Event{Id: "3", Event: "message", Data: `{"user":"gin"}`}, event)
+
+	hub.Close("lobby")
+	_, err = d.Decode()
+	assert.Error(t, err)
+}
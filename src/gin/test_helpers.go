@@ -0,0 +1,41 @@
+package gin
+
+import (
+	"context"
+	"net/http"
+)
+
+// CreateTestContext returns a context writing to w and its new engine, so a handler
+// can be called without the router. The Request of the context is set by the caller,
+// and as no server cancels it the context is never done, see CreateTestContextWithRequest.
+func CreateTestContext(w http.ResponseWriter) (c *Context, e *Engine) {
+	e = New()
+	c = e.allocateContext()
+	c.writermen.reset(w)
+	c.reset()
+	c.ctx = context.Background()
+	return c, e
+}
+
+// CreateTestContextWithRequest is CreateTestContext serving req. The context is done when
+// req.Context() is, so the test cancels it, or sets its deadline, to play the client going away.
+func CreateTestContextWithRequest(w http.ResponseWriter, req *http.Request) (c *Context, e *Engine) {
+	c, e = CreateTestContext(w)
+	c.Request = req
+	c.ctx = req.Context()
+	return c, e
+}
+
+// AddParam adds a route parameter to the context, such as the id of "/user/:id".
+func (c *Context) AddParam(key, value string) {
+	c.Params = append(c.Params, Param{key: key, value: value})
+}
+
+// RunHandlers calls handlers as the chain of the context, then writes the header if the
+// handlers wrote no body, as it is done after serving a request.
+func (c *Context) RunHandlers(handlers ...HandlerFunc) {
+	c.handlers = handlers
+	c.index = -1
+	c.Next()
+	c.Writer.WriteHeaderNow()
+}
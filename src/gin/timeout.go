@@ -0,0 +1,28 @@
+package gin
+
+import (
+	"context"
+	"time"
+)
+
+// Timeout returns a middleware setting a deadline of d on the request context, for the engine or for a route.
+// The handlers are not interrupted: they should watch c.Done() or pass c to the calls they make.
+// When the deadline has expired and nothing was written, the response is 503 Service Unavailable.
+func Timeout(d time.Duration) HandlerFunc {
+	return func(c *Context) {
+		parent := c.ctx
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, cancel := context.WithTimeout(parent, d)
+		defer cancel()
+
+		c.ctx = ctx
+		c.Next()
+		c.ctx = parent
+
+		if ctx.Err() == context.DeadlineExceeded && !c.Writer.Written() {
+			c.AbortWithStatus(503)
+		}
+	}
+}
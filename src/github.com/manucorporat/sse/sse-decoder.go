@@ -0,0 +1,120 @@
+// Copyright 2014 Manu Martinez-Almeida.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package sse
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Decoder reads the events of a text/event-stream, as an EventSource does.
+// The Data of the decoded events is a string, the lines of a multi-line data are joined by "\n".
+// The Id is the last event ID of the stream, so it is kept by the events without an id field.
+type Decoder struct {
+	r      *bufio.Reader
+	lastId string
+	first  bool
+}
+
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), first: true}
+}
+
+// Decode returns the next event, or io.EOF when the stream ends.
+// An event not terminated by a blank line at the end of the stream is discarded.
+func (d *Decoder) Decode() (Event, error) {
+	var event Event
+	var data []string
+	hasData := false
+
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			return Event{}, err
+		}
+
+		if line == "" {
+			if !hasData {
+				// no event to dispatch, the event type is reset
+				event = Event{}
+				continue
+			}
+			event.Id = d.lastId
+			event.Data = strings.Join(data, "\n")
+			return event, nil
+		}
+		if line[0] == ':' {
+			// comment, such as a keepalive
+			continue
+		}
+
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "id":
+			if !strings.ContainsRune(value, 0) {
+				d.lastId = value
+			}
+		case "retry":
+			if retry, err := strconv.ParseUint(value, 10, 32); err == nil {
+				event.Retry = uint(retry)
+			}
+		}
+	}
+}
+
+// The readLine returns a line without its end of line, CRLF, LF or CR.
+func (d *Decoder) readLine() (string, error) {
+	var line []byte
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch b {
+		case '\n':
+			return d.trimBOM(line), nil
+		case '\r':
+			if next, err := d.r.Peek(1); err == nil && next[0] == '\n' {
+				d.r.ReadByte()
+			}
+			return d.trimBOM(line), nil
+		}
+		line = append(line, b)
+	}
+}
+
+func (d *Decoder) trimBOM(line []byte) string {
+	if d.first {
+		d.first = false
+		return strings.TrimPrefix(string(line), "\ufeff")
+	}
+	return string(line)
+}
+
+// Decode reads every event of r.
+func Decode(r io.Reader) ([]Event, error) {
+	var events []Event
+	d := NewDecoder(r)
+	for {
+		event, err := d.Decode()
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2014 Manu Martinez-Almeida.  All rights reserved.
+// Use of this source code is governed by a MIT style
+// license that can be found in the LICENSE file.
+
+package sse
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeEncoded(t *testing.T) {
+	w := new(bytes.Buffer)
+	Encode(w, Event{Event: "float", Data: 1.5})
+	Encode(w, Event{Id: "123", Data: map[string]interface{}{"foo": "bar"}})
+	Encode(w, Event{Id: "124", Event: "chat", Retry: 10, Data: "hi! dude"})
+
+	events, err := Decode(w)
+	assert.NoError(t, err)
+	assert.Equal(t, events, []Event{
+		{Event: "float", Data: "1.5"},
+		{Id: "123", Data: `{"foo":"bar"}`},
+		{Id: "124", Event: "chat", Retry: 10, Data: "hi! dude"},
+	})
+}
+
+func TestDecodeStream(t *testing.T) {
+	stream := "\ufeff: keepalive\r\n" +
+		"data: first\r\ndata:second\r\nid: 7\r\n\r\n" +
+		"event: empty\n\n" +
+		"retry: x\nevent: next\ndata\n\n" +
+		"id\rdata: third\r\r" +
+		"data: unterminated"
+
+	d := NewDecoder(strings.NewReader(stream))
+	event, err := d.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, event, Event{Id: "7", Data: "first\nsecond"})
+
+	event, err = d.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, event, Event{Id: "7", Event: "next", Data: ""})
+
+	event, err = d.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, event, Event{Id: "", Data: "third"})
+
+	_, err = d.Decode()
+	assert.Equal(t, err, io.EOF)
+}
@@ -0,0 +1,120 @@
+// Command go_spider runs the crawl job described by a job config file,
+// so that crawls are launched without writing Go code. See spider.NewJob for the file format.
+//
+// Usage:
+//
+//	go_spider [-set key=value]... [-seed url]... job.conf
+//
+// A -set flag overrides a value of the file: "threads=8" sets a global key and
+// "scheduler.type=file" sets the key of a section. The first interrupt stops the crawl
+// after the pages in process are finished, and a second one exits at once.
+//
+// The pipelines, the scheduler and the log file of the job are closed when the crawl ends,
+// or by the job itself when it fails to start. A second interrupt exits without closing them:
+// the items buffered by pipelines are lost, while a file scheduler, which writes every push
+// and poll to its log at once, resumes from its log at the next start.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+import (
+	"go_spider/core/common/config"
+	"go_spider/core/spider"
+)
+
+type listFlag []string
+
+func (this *listFlag) String() string {
+	return strings.Join(*this, ",")
+}
+
+func (this *listFlag) Set(value string) error {
+	*this = append(*this, value)
+	return nil
+}
+
+func main() {
+	var sets, seeds listFlag
+	flag.Var(&sets, "set", "override a job value, as key=value or section.key=value")
+	flag.Var(&seeds, "seed", "add a seed url")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: go_spider [-set key=value]... [-seed url]... job.conf")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	path := flag.Arg(0)
+	conf, err := loadConfig(path, sets, seeds)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "go_spider : "+err.Error())
+		os.Exit(1)
+	}
+	s, err := spider.NewJob(conf, filepath.Dir(path))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "go_spider : "+err.Error())
+		os.Exit(1)
+	}
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		fmt.Fprintln(os.Stderr, "go_spider : stopping, interrupt again to exit at once")
+		s.Stop()
+		<-signals
+		os.Exit(1)
+	}()
+
+	s.Run()
+
+	st := s.GetStats()
+	fmt.Printf("downloaded %d, failed %d, process failed %d, skipped %d, retried %d, items %d in %s\n",
+		st.Downloaded, st.Failed, st.ProcessFailed, st.Skipped, st.Retried, st.Items, time.Since(st.StartTime).Round(time.Second))
+}
+
+// The loadConfig reads the job config file and applies the -set and -seed flags to it.
+func loadConfig(path string, sets []string, seeds []string) (*config.Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	conf := config.NewConfig()
+	if err = conf.LoadString(string(b)); err != nil {
+		return nil, err
+	}
+
+	for _, set := range sets {
+		pair := strings.SplitN(set, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("bad -set %q, want key=value", set)
+		}
+		key := strings.TrimSpace(pair[0])
+		value := strings.TrimSpace(pair[1])
+		// section names may hold dots, as "pipeline.out", so the key is after the last one
+		if i := strings.LastIndex(key, "."); i >= 0 {
+			conf.SectionSet(key[:i], key[i+1:], value)
+		} else {
+			conf.GlobalSet(key, value)
+		}
+	}
+
+	if len(seeds) > 0 {
+		urls := conf.SectionGetSlice("seeds", "urls", ",")
+		conf.SectionSet("seeds", "urls", strings.Join(append(urls, seeds...), ","))
+	}
+	return conf, nil
+}
@@ -17,7 +17,7 @@ type Config struct {
 const emptyRunes = " \r\t\v"
 
 func NewConfig() *Config {
-	return &Config{globalContent: make(map[string]string), sectionContent: make(map[string]map[string]string)}
+	return &Config{globalContent: make(map[string]string), sectionContents: make(map[string]map[string]string)}
 }
 
 func (this *Config) Load(configFile string) *Config {
@@ -151,6 +151,12 @@ func (this *Config) GlobalGetInt64(key string) int64 {
 	return result
 }
 
+// GlobalGetBool returns false when the value is empty or not a bool such as "true", "1" or "false".
+func (this *Config) GlobalGetBool(key string) bool {
+	result, _ := strconv.ParseBool(this.GlobalGet(key))
+	return result
+}
+
 func (this *Config) GlobalGetDuration(key string) time.Duration {
 	return time.Duration(this.GlobalGetInt(key)) * time.Second
 }
@@ -222,6 +228,9 @@ func (this *Config) SectionSet(section string, key string, value string) {
 		content = make(map[string]string)
 		content[key] = value
 		this.sectionContents[section] = content
+		if !this.hasSectionName(section) {
+			this.sections = append(this.sections, section)
+		}
 	}
 }
 
@@ -237,6 +246,12 @@ func (this *Config) SectionGetInt(section string, key string) int {
 	return result
 }
 
+// SectionGetBool returns false when the value is empty or not a bool such as "true", "1" or "false".
+func (this *Config) SectionGetBool(section string, key string) bool {
+	result, _ := strconv.ParseBool(this.SectionGet(section, key))
+	return result
+}
+
 func (this *Config) SectionGetDuration(section string, key string) time.Duration {
 	return time.Duration(this.SectionGetInt(section, key)) * time.Second
 }
@@ -259,3 +274,12 @@ func (this *Config) SectionContent(section string) map[string]string {
 func (this *Config) SectionContents() map[string]map[string]string {
 	return this.sectionContents
 }
+
+func (this *Config) hasSectionName(section string) bool {
+	for _, s := range this.sections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
@@ -1,17 +1,17 @@
 package mlog
 
 import (
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strconv"
-	"time"
+	"sync"
 )
 
 type filelog struct {
 	plog
-	loginst *log.Logger
+	locker sync.Mutex
+	logger *Logger
 }
 
 var flog *filelog
@@ -27,7 +27,7 @@ func LogInst() *filelog {
 func InitFilelog(isopen bool, fp string) {
 	if !isopen {
 		flog = &filelog{}
-		flog.loginst = nil
+		flog.logger = NewLogger(DiscardSink)
 		flog.isopen = isopen
 		return
 	}
@@ -51,39 +51,38 @@ func InitFilelog(isopen bool, fp string) {
 }
 
 // The newFilelog returns initialized filelog object.
-// The default file path is "WORKDIR/log/log.2011-01-01".
+// The file path is "WORKDIR/log/log.2011-1-1" and a new file is started every day.
 func newFilelog(isopen bool, logpath string) *filelog {
-	year, month, day := time.Now().Date()
-	filename := "log." + strconv.Itoa(year) + "-" + strconv.Itoa(int(month)) + "-" + strconv.Itoa(day)
-	err := os.MkdirAll(logpath, 0755)
-	if err != nil {
-		panic("logpath error : " + logpath + "\n")
-	}
-
-	f, err := os.OpenFile(logpath+"/"+filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
-	if err != nil {
-		panic("log file open error : " + logpath + "/" + filename + "\n")
-	}
-
 	pfilelog := &filelog{}
-	pfilelog.loginst = log.New(f, "", log.LstdFlags)
+	pfilelog.logger = NewLogger(NewTextSink(NewRotateFile(logpath, "log").SetDayLayout("2006-1-2")))
 	pfilelog.isopen = isopen
 	return pfilelog
 }
 
+// SetLogger sends the log to logger, to plug another sink in.
+func (this *filelog) SetLogger(logger *Logger) {
+	this.locker.Lock()
+	this.logger = logger
+	this.locker.Unlock()
+}
+
 func (this *filelog) LogError(str string) {
-	this.log("[ERROR]", str)
+	this.log(LevelError, str)
 }
 
 func (this *filelog) LogInfo(str string) {
-	this.log("[INFO]", str)
+	this.log(LevelInfo, str)
 }
 
-func (this *filelog) log(label string, str string) {
+func (this *filelog) log(level Level, str string) {
 	if !this.isopen {
 		return
 	}
 
+	this.locker.Lock()
+	logger := this.logger
+	this.locker.Unlock()
+
 	file, line := this.getCaller()
-	this.loginst.Printf("%s:%d: %s %s\n", file, line, label, str)
+	logger.Log(level, str, F("caller", file+":"+strconv.Itoa(line)))
 }
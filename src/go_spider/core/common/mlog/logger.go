@@ -0,0 +1,140 @@
+package mlog
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Level is the severity of a log entry.
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+func (this Level) String() string {
+	switch this {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	}
+	return "LEVEL" + strconv.Itoa(int(this))
+}
+
+// ParseLevel parses "debug", "info", "warn" or "error" in any case.
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, errors.New("unknown log level : " + s)
+}
+
+// Field is a key/value pair attached to a log entry, such as the task name or the url.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F returns the Field key=value.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Entry is one log record given to a Sink.
+type Entry struct {
+	Time   time.Time
+	Level  Level
+	Msg    string
+	Fields []Field
+}
+
+// The Sink interface writes log entries. Implement it to send logs to your own backend.
+// Write is called by many goroutines at once.
+type Sink interface {
+	Write(e *Entry) error
+}
+
+// Logger is a leveled logger writing entries with key/value fields to a Sink.
+// Loggers made by With share the sink and the level of their parent.
+type Logger struct {
+	sink   Sink
+	level  *int32
+	fields []Field
+}
+
+// NewLogger writes the entries of level info and above to sink.
+func NewLogger(sink Sink) *Logger {
+	level := int32(LevelInfo)
+	return &Logger{sink: sink, level: &level}
+}
+
+// SetLevel sets the lowest level written, for this logger and the loggers sharing its level.
+func (this *Logger) SetLevel(level Level) *Logger {
+	atomic.StoreInt32(this.level, int32(level))
+	return this
+}
+
+func (this *Logger) GetLevel() Level {
+	return Level(atomic.LoadInt32(this.level))
+}
+
+// Enabled reports whether entries of level are written, to skip building expensive fields.
+func (this *Logger) Enabled(level Level) bool {
+	return level >= this.GetLevel()
+}
+
+// With returns a logger adding fields to every entry.
+func (this *Logger) With(fields ...Field) *Logger {
+	all := make([]Field, 0, len(this.fields)+len(fields))
+	all = append(all, this.fields...)
+	all = append(all, fields...)
+	return &Logger{sink: this.sink, level: this.level, fields: all}
+}
+
+func (this *Logger) Debug(msg string, fields ...Field) {
+	this.Log(LevelDebug, msg, fields...)
+}
+
+func (this *Logger) Info(msg string, fields ...Field) {
+	this.Log(LevelInfo, msg, fields...)
+}
+
+func (this *Logger) Warn(msg string, fields ...Field) {
+	this.Log(LevelWarn, msg, fields...)
+}
+
+func (this *Logger) Error(msg string, fields ...Field) {
+	this.Log(LevelError, msg, fields...)
+}
+
+// Log writes msg at level. Errors of the sink are dropped, as there is no better place to report them.
+func (this *Logger) Log(level Level, msg string, fields ...Field) {
+	if !this.Enabled(level) {
+		return
+	}
+	all := this.fields
+	if len(fields) > 0 {
+		all = make([]Field, 0, len(this.fields)+len(fields))
+		all = append(all, this.fields...)
+		all = append(all, fields...)
+	}
+	this.sink.Write(&Entry{Time: time.Now(), Level: level, Msg: msg, Fields: all})
+}
@@ -0,0 +1,80 @@
+package mlog
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(NewTextSink(&buf)).With(F("task", "a"))
+	logger.Debug("hidden")
+	logger.Info("crawled", F("url", "http://a.com/x"), F("status", 200), F("duration", 1500*time.Millisecond))
+	logger.Error("failed", F("error", errors.New("read timeout")))
+
+	out := buf.String()
+	if strings.Contains(out, "hidden") {
+		t.Error("debug entry should be dropped at level info")
+	}
+	if !strings.Contains(out, "[INFO] crawled task=a url=http://a.com/x status=200 duration=1.5s\n") {
+		t.Error("text sink error : " + out)
+	}
+	if !strings.Contains(out, `[ERROR] failed task=a error="read timeout"`) {
+		t.Error("text sink quote error : " + out)
+	}
+
+	buf.Reset()
+	logger = NewLogger(NewJsonSink(&buf))
+	child := logger.With(F("task", "b"))
+	logger.SetLevel(LevelDebug)
+	child.Debug("start", F("url", "http://b.com"))
+	if !strings.Contains(buf.String(), `"level":"debug","msg":"start","task":"b","url":"http://b.com"`) {
+		t.Error("json sink error : " + buf.String())
+	}
+
+	if level, err := ParseLevel("WARN"); err != nil || level != LevelWarn {
+		t.Error("parse level error")
+	}
+}
+
+func TestRotateFile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "mlog")
+	defer os.RemoveAll(dir)
+
+	f := NewRotateFile(dir, "spider").SetMaxSize(10).SetMaxFiles(2)
+	for i := 0; i < 4; i++ {
+		f.Write([]byte("12345678\n"))
+	}
+	f.Close()
+
+	names, _ := filepath.Glob(filepath.Join(dir, "spider.*"))
+	if len(names) != 2 {
+		t.Errorf("files should be rotated by size and the oldest removed : %v", names)
+	}
+	day := time.Now().Format("2006-01-02")
+	if _, err := os.Stat(filepath.Join(dir, "spider."+day+".3")); err != nil {
+		t.Error("newest file is missing : " + err.Error())
+	}
+
+	// the days of the layout are ordered by date, "2011-9-30" before "2011-10-1"
+	ioutil.WriteFile(filepath.Join(dir, "log.2011-9-30"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "log.2011-10-1"), nil, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "log.other"), nil, 0644)
+	f = NewRotateFile(dir, "log").SetDayLayout("2006-1-2").SetMaxFiles(2)
+	f.Write([]byte("1\n"))
+	f.Close()
+	names, _ = filepath.Glob(filepath.Join(dir, "log.*"))
+	day = time.Now().Format("2006-1-2")
+	if len(names) != 3 || names[0] != filepath.Join(dir, "log.2011-10-1") || names[2] != filepath.Join(dir, "log.other") {
+		t.Errorf("the oldest day should be removed : %v", names)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "log."+day)); err != nil {
+		t.Error("file of the day is missing : " + err.Error())
+	}
+}
@@ -0,0 +1,173 @@
+package mlog
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The RotateFile is an io.Writer appending to "dir/prefix.2006-01-02", which starts a new file every day
+// and, when maxSize is set, every maxSize bytes as "dir/prefix.2006-01-02.1", ".2" and so on.
+// The day is formatted by the layout set by SetDayLayout.
+// When maxFiles is set, the oldest files of prefix are removed to keep at most maxFiles.
+type RotateFile struct {
+	locker   *sync.Mutex
+	dir      string
+	prefix   string
+	layout   string
+	maxSize  int64
+	maxFiles int
+
+	file *os.File
+	day  string
+	seq  int
+	size int64
+}
+
+// NewRotateFile creates dir if needed. The file is opened by the first Write.
+func NewRotateFile(dir string, prefix string) *RotateFile {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		panic("logpath error : " + dir + "\n")
+	}
+	return &RotateFile{locker: new(sync.Mutex), dir: dir, prefix: prefix, layout: "2006-01-02"}
+}
+
+// SetDayLayout sets the time layout of the day in file names, "2006-01-02" by default.
+func (this *RotateFile) SetDayLayout(layout string) *RotateFile {
+	this.layout = layout
+	return this
+}
+
+// SetMaxSize sets the size in bytes a file is rotated at, 0 rotates daily only.
+func (this *RotateFile) SetMaxSize(n int64) *RotateFile {
+	this.maxSize = n
+	return this
+}
+
+// SetMaxFiles sets how many files of prefix are kept, 0 keeps all.
+func (this *RotateFile) SetMaxFiles(n int) *RotateFile {
+	this.maxFiles = n
+	return this
+}
+
+func (this *RotateFile) Write(b []byte) (int, error) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	day := time.Now().Format(this.layout)
+	if this.file == nil || day != this.day || (this.maxSize > 0 && this.size+int64(len(b)) > this.maxSize && this.size > 0) {
+		if err := this.rotate(day); err != nil {
+			return 0, err
+		}
+	}
+	n, err := this.file.Write(b)
+	this.size += int64(n)
+	return n, err
+}
+
+func (this *RotateFile) Close() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if this.file == nil {
+		return nil
+	}
+	err := this.file.Close()
+	this.file = nil
+	return err
+}
+
+// The rotate opens the next file of day. An existing file is appended to when it has room left,
+// so a restarted process continues the file of the day.
+func (this *RotateFile) rotate(day string) error {
+	if this.file != nil {
+		this.file.Close()
+		this.file = nil
+	}
+	if day != this.day {
+		this.day = day
+		this.seq = 0
+	} else {
+		this.seq++
+	}
+
+	for {
+		name := this.name(day, this.seq)
+		info, err := os.Stat(name)
+		if err == nil && this.maxSize > 0 && info.Size() >= this.maxSize {
+			this.seq++
+			continue
+		}
+		f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		this.file = f
+		this.size = 0
+		if info != nil {
+			this.size = info.Size()
+		}
+		break
+	}
+
+	this.removeOld()
+	return nil
+}
+
+func (this *RotateFile) name(day string, seq int) string {
+	name := filepath.Join(this.dir, this.prefix+"."+day)
+	if seq > 0 {
+		name += "." + strconv.Itoa(seq)
+	}
+	return name
+}
+
+// The removeOld removes the oldest files over maxFiles, ordered by day then by sequence.
+// Files of prefix whose day does not match the layout are kept.
+func (this *RotateFile) removeOld() {
+	if this.maxFiles <= 0 {
+		return
+	}
+	matches, err := filepath.Glob(filepath.Join(this.dir, this.prefix+".*"))
+	if err != nil {
+		return
+	}
+
+	type logFile struct {
+		name string
+		day  time.Time
+		seq  int
+	}
+	var files []logFile
+	for _, name := range matches {
+		rest := strings.TrimPrefix(filepath.Base(name), this.prefix+".")
+		parts := strings.SplitN(rest, ".", 2)
+		day, err := time.Parse(this.layout, parts[0])
+		if err != nil {
+			continue
+		}
+		seq := 0
+		if len(parts) == 2 {
+			if seq, err = strconv.Atoi(parts[1]); err != nil {
+				continue
+			}
+		}
+		files = append(files, logFile{name, day, seq})
+	}
+	if len(files) <= this.maxFiles {
+		return
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		if !files[i].day.Equal(files[j].day) {
+			return files[i].day.Before(files[j].day)
+		}
+		return files[i].seq < files[j].seq
+	})
+	for _, f := range files[:len(files)-this.maxFiles] {
+		os.Remove(f.name)
+	}
+}
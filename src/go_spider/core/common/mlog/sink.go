@@ -0,0 +1,145 @@
+package mlog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The TextSink writes one line per entry as:
+//
+//	2015/01/02 15:04:05 [INFO] start crawl task=github url=http://github.com/
+//
+// Values holding spaces or quotes are quoted.
+type TextSink struct {
+	locker *sync.Mutex
+	w      io.Writer
+}
+
+func NewTextSink(w io.Writer) *TextSink {
+	return &TextSink{locker: new(sync.Mutex), w: w}
+}
+
+func (this *TextSink) Write(e *Entry) error {
+	var buf bytes.Buffer
+	buf.WriteString(e.Time.Format("2006/01/02 15:04:05"))
+	buf.WriteString(" [" + e.Level.String() + "] ")
+	buf.WriteString(e.Msg)
+	for _, f := range e.Fields {
+		buf.WriteString(" " + f.Key + "=")
+		s := fieldString(f.Value)
+		if s == "" || strings.ContainsAny(s, " \t\r\n\"=") {
+			s = strconv.Quote(s)
+		}
+		buf.WriteString(s)
+	}
+	buf.WriteByte('\n')
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	_, err := this.w.Write(buf.Bytes())
+	return err
+}
+
+// The JsonSink writes one json object per line with the keys "time", "level", "msg" and the fields in order.
+type JsonSink struct {
+	locker *sync.Mutex
+	w      io.Writer
+}
+
+func NewJsonSink(w io.Writer) *JsonSink {
+	return &JsonSink{locker: new(sync.Mutex), w: w}
+}
+
+func (this *JsonSink) Write(e *Entry) error {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":"` + e.Time.Format(time.RFC3339Nano) + `","level":"` + strings.ToLower(e.Level.String()) + `","msg":`)
+	b, _ := json.Marshal(e.Msg)
+	buf.Write(b)
+	for _, f := range e.Fields {
+		var value interface{} = f.Value
+		switch v := f.Value.(type) {
+		case error:
+			value = v.Error()
+		case time.Duration:
+			value = v.String()
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			b, _ = json.Marshal(fieldString(f.Value))
+		}
+		key, _ := json.Marshal(f.Key)
+		buf.WriteByte(',')
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(b)
+	}
+	buf.WriteString("}\n")
+
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	_, err := this.w.Write(buf.Bytes())
+	return err
+}
+
+// The MultiSink writes every entry to all of its sinks.
+type MultiSink []Sink
+
+func NewMultiSink(sinks ...Sink) MultiSink {
+	return MultiSink(sinks)
+}
+
+// Write returns the first error of the sinks, after writing to every sink.
+func (this MultiSink) Write(e *Entry) error {
+	var first error
+	for _, s := range this {
+		if err := s.Write(e); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// The LevelSink passes only the entries of level and above to another sink,
+// such as errors to a file while everything goes to the console.
+type LevelSink struct {
+	level Level
+	sink  Sink
+}
+
+func NewLevelSink(level Level, sink Sink) *LevelSink {
+	return &LevelSink{level: level, sink: sink}
+}
+
+func (this *LevelSink) Write(e *Entry) error {
+	if e.Level < this.level {
+		return nil
+	}
+	return this.sink.Write(e)
+}
+
+// DiscardSink drops every entry.
+var DiscardSink Sink = discardSink{}
+
+type discardSink struct{}
+
+func (discardSink) Write(e *Entry) error {
+	return nil
+}
+
+func fieldString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
@@ -1,14 +1,13 @@
 package mlog
 
 import (
-	"log"
 	"os"
 )
 
 type strace struct {
 	plog
 
-	loginst *log.Logger
+	logger *Logger
 }
 
 var pstrace *strace
@@ -23,7 +22,7 @@ func StraceInst() *strace {
 
 func newStrace() *strace {
 	pstrace := &strace{}
-	pstrace.loginst = log.New(os.Stderr, "", log.LstdFlags)
+	pstrace.logger = NewLogger(NewTextSink(os.Stderr))
 	pstrace.isopen = true
 	return pstrace
 }
@@ -33,5 +32,10 @@ func (this *strace) Println(str string) {
 		return
 	}
 
-	this.loginst.Printf("%s\n", str)
+	this.logger.Info(str)
+}
+
+// SetLogger sends the trace to logger, to plug another sink in.
+func (this *strace) SetLogger(logger *Logger) {
+	this.logger = logger
 }
@@ -23,6 +23,8 @@ type Page struct {
 	req      *request.Request
 	body     string
 
+	statusCode int
+
 	header  http.Header
 	cookies []*http.Cookie
 
@@ -48,6 +50,17 @@ func (this *Page) GetHeader() http.Header {
 	return this.header
 }
 
+// SetStatusCode saves the status code of http responce.
+func (this *Page) SetStatusCode(statusCode int) *Page {
+	this.statusCode = statusCode
+	return this
+}
+
+// GetStatusCode returns the status code of http responce, or 0 if no responce was received.
+func (this *Page) GetStatusCode() int {
+	return this.statusCode
+}
+
 // SetHeader save the cookies of http responce
 func (this *Page) SetCookies(cookies []*http.Cookie) {
 	this.cookies = cookies
@@ -79,6 +92,17 @@ func (this *Page) AddField(key string, value string) {
 	this.pItems.AddItem(key, value)
 }
 
+// AddFieldValue saves a field with a json compatible value, such as a number, a list or a nested record.
+func (this *Page) AddFieldValue(key string, value interface{}) {
+	this.pItems.SetValue(key, value)
+}
+
+// AddRecord saves one of many records parsed from the page, such as one row of a list page.
+// The fields added by AddField and AddFieldValue are added to every record.
+func (this *Page) AddRecord(record page_items.Record) {
+	this.pItems.AddRecord(record)
+}
+
 // GetPageItems returns PageItems object that record KV pair parsed in PageProcesser.
 func (this *Page) GetPageItems() *page_items.PageItems {
 	return this.pItems
@@ -1,20 +1,31 @@
 package page_items
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 import (
 	"go_spider/core/common/request"
 )
 
+// Record is one item parsed from a page. Values are json compatible:
+// string, bool, numbers, nil, []interface{}, map[string]interface{} or nested Record.
+type Record map[string]interface{}
+
 // PageItems represents an entity save result parsed by PageProcesser and will be output at last.
 type PageItems struct {
 	req *request.Request
-	// The items is the container of parsed result.
-	items map[string]string
+	// The items is the container of parsed fields of the page.
+	items Record
+	// The records is the container of records when a page holds many items.
+	records []Record
 	// The skip represents whether send ResultItems to scheduler or not.
 	skip bool
 }
 
 func NewPageItems(req *request.Request) *PageItems {
-	items := make(map[string]string)
+	items := make(Record)
 	return &PageItems{req: req, items: items}
 }
 
@@ -26,15 +37,79 @@ func (this *PageItems) AddItem(key string, item string) {
 	this.items[key] = item
 }
 
+// GetItem returns the field as string. Values that are not strings are json encoded.
 func (this *PageItems) GetItem(key string) (string, bool) {
 	t, ok := this.items[key]
-	return t, ok
+	if !ok {
+		return "", false
+	}
+	return ValueString(t), true
 }
 
+// GetAll returns all fields as strings. Values that are not strings are json encoded.
 func (this *PageItems) GetAll() map[string]string {
+	all := make(map[string]string, len(this.items))
+	for key, value := range this.items {
+		all[key] = ValueString(value)
+	}
+	return all
+}
+
+// SetValue saves a field with a json compatible value.
+func (this *PageItems) SetValue(key string, value interface{}) {
+	this.items[key] = value
+}
+
+func (this *PageItems) GetValue(key string) (interface{}, bool) {
+	t, ok := this.items[key]
+	return t, ok
+}
+
+// GetValues returns the fields of the page with their typed values.
+func (this *PageItems) GetValues() Record {
 	return this.items
 }
 
+// AddRecord adds one record for pages that hold many items, such as a list page.
+func (this *PageItems) AddRecord(record Record) {
+	this.records = append(this.records, record)
+}
+
+// GetRecords returns the records added by AddRecord.
+// A page without records but with fields returns its fields as the only record.
+// The fields of a page with records are shared by all of them, so they are added to a copy of
+// every record, where a key of the record wins over the field of the same name.
+func (this *PageItems) GetRecords() []Record {
+	if len(this.records) == 0 && len(this.items) != 0 {
+		return []Record{this.items}
+	}
+	if len(this.items) == 0 {
+		return this.records
+	}
+	records := make([]Record, 0, len(this.records))
+	for _, record := range this.records {
+		merged := make(Record, len(this.items)+len(record))
+		for key, value := range this.items {
+			merged[key] = value
+		}
+		for key, value := range record {
+			merged[key] = value
+		}
+		records = append(records, merged)
+	}
+	return records
+}
+
+// Validate checks every record against schema.
+func (this *PageItems) Validate(schema *Schema) error {
+	for _, record := range this.GetRecords() {
+		if err := schema.Validate(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (this *PageItems) GetSkip() bool {
 	return this.skip
 }
@@ -43,3 +118,18 @@ func (this *PageItems) SetSkip(skip bool) *PageItems {
 	this.skip = skip
 	return this
 }
+
+// ValueString returns string values unchanged and other values json encoded.
+func ValueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(b)
+}
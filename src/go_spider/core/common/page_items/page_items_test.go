@@ -0,0 +1,89 @@
+package page_items
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+import (
+	"go_spider/core/common/request"
+)
+
+func TestPageItems(t *testing.T) {
+	items := NewPageItems(request.NewRequest("http://baidu.com", "html", "", "GET", "", nil, nil, nil, nil))
+	items.AddItem("name", "go_spider")
+	items.SetValue("stars", 1024)
+	items.SetValue("tags", []interface{}{"go", "spider"})
+
+	all := items.GetAll()
+	fmt.Println(all)
+	if all["name"] != "go_spider" || all["stars"] != "1024" || all["tags"] != `["go","spider"]` {
+		t.Error("string api error")
+	}
+	if v, _ := items.GetValue("stars"); v != 1024 {
+		t.Error("typed value error")
+	}
+
+	records := items.GetRecords()
+	if len(records) != 1 || records[0]["name"] != "go_spider" {
+		t.Error("page fields should be the only record")
+	}
+
+	items.AddRecord(Record{"name": "a"})
+	items.AddRecord(Record{"name": "b"})
+	if records = items.GetRecords(); len(records) != 2 {
+		t.Error("records error")
+	}
+}
+
+func TestPageItemsRecordsWithFields(t *testing.T) {
+	items := NewPageItems(request.NewRequest("http://baidu.com", "html", "", "GET", "", nil, nil, nil, nil))
+	items.AddItem("page", "http://baidu.com")
+	items.SetValue("name", "page name")
+	items.AddRecord(Record{"name": "a"})
+	items.AddRecord(Record{"name": "b", "stars": 3})
+
+	records := items.GetRecords()
+	if len(records) != 2 {
+		t.Fatalf("%d records, want 2", len(records))
+	}
+	if records[0]["page"] != "http://baidu.com" || records[0]["name"] != "a" ||
+		records[1]["page"] != "http://baidu.com" || records[1]["name"] != "b" || records[1]["stars"] != 3 {
+		t.Errorf("the page fields should be merged into every record : %v", records)
+	}
+	if _, ok := items.records[0]["page"]; ok {
+		t.Error("the added records should not be changed")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	var schema Schema
+	err := json.Unmarshal([]byte(`{"strict": true, "fields": [
+		{"name": "name", "type": "string", "required": true},
+		{"name": "stars", "type": "int"},
+		{"name": "repos", "type": "list", "schema": {"fields": [{"name": "url", "type": "string", "required": true}]}}
+	]}`), &schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok := Record{"name": "a", "stars": 3, "repos": []interface{}{map[string]interface{}{"url": "x"}}}
+	if err := schema.Validate(ok); err != nil {
+		t.Error(err)
+	}
+
+	bad := []Record{
+		{"stars": 3},
+		{"name": "a", "stars": "3"},
+		{"name": "a", "repos": []interface{}{map[string]interface{}{}}},
+		{"name": "a", "other": 1},
+	}
+	for _, r := range bad {
+		err := schema.Validate(r)
+		fmt.Println(err)
+		if err == nil {
+			t.Errorf("record should not match schema : %v", r)
+		}
+	}
+}
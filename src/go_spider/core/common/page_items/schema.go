@@ -0,0 +1,139 @@
+package page_items
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+)
+
+// Schema describes the fields a Record must or may have.
+type Schema struct {
+	Fields []*SchemaField `json:"fields"`
+	// The Strict rejects records holding fields not listed in Fields.
+	Strict bool `json:"strict"`
+}
+
+// SchemaField describes one field of a Record.
+type SchemaField struct {
+	Name string `json:"name"`
+	// The Type is "string", "int", "float", "bool", "list", "object" or "" for any value.
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+	// The Schema checks the records of an "object" field or of the objects in a "list" field.
+	Schema *Schema `json:"schema"`
+}
+
+// Validate returns an error naming the first field of record that does not match the schema.
+func (this *Schema) Validate(record Record) error {
+	for _, f := range this.Fields {
+		value, ok := record[f.Name]
+		if !ok || value == nil {
+			if f.Required {
+				return errors.New("field " + f.Name + " is required")
+			}
+			continue
+		}
+		if !f.match(value) {
+			return errors.New("field " + f.Name + " is not " + f.Type)
+		}
+		if f.Schema != nil {
+			if err := f.validateNested(value); err != nil {
+				return errors.New("field " + f.Name + " : " + err.Error())
+			}
+		}
+	}
+
+	if this.Strict {
+		for name := range record {
+			if this.field(name) == nil {
+				return errors.New("field " + name + " is not in schema")
+			}
+		}
+	}
+	return nil
+}
+
+func (this *Schema) field(name string) *SchemaField {
+	for _, f := range this.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (this *SchemaField) match(value interface{}) bool {
+	switch this.Type {
+	case "":
+		return true
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "bool":
+		_, ok := value.(bool)
+		return ok
+	case "int":
+		if n, ok := value.(json.Number); ok {
+			_, err := n.Int64()
+			return err == nil
+		}
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		}
+		// json decoded numbers are float64
+		if f, ok := value.(float64); ok {
+			return f == float64(int64(f))
+		}
+		return false
+	case "float":
+		if _, ok := value.(json.Number); ok {
+			return true
+		}
+		switch reflect.ValueOf(value).Kind() {
+		case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		}
+		return false
+	case "list":
+		kind := reflect.ValueOf(value).Kind()
+		return kind == reflect.Slice || kind == reflect.Array
+	case "object":
+		_, ok := toRecord(value)
+		return ok
+	}
+	return false
+}
+
+func (this *SchemaField) validateNested(value interface{}) error {
+	if record, ok := toRecord(value); ok {
+		return this.Schema.Validate(record)
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil
+	}
+	for i := 0; i < v.Len(); i++ {
+		record, ok := toRecord(v.Index(i).Interface())
+		if !ok {
+			return errors.New("list element is not object")
+		}
+		if err := this.Schema.Validate(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toRecord(value interface{}) (Record, bool) {
+	switch v := value.(type) {
+	case Record:
+		return v, true
+	case map[string]interface{}:
+		return Record(v), true
+	}
+	return nil, false
+}
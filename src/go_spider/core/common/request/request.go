@@ -1,6 +1,7 @@
 package request
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -32,20 +33,25 @@ type Request struct {
 	checkRedirect func(req *http.Request, via []*http.Request) error
 
 	meta interface{}
+
+	// The depth is the number of links followed from a seed request to this one.
+	depth int
+	// The priority orders requests in schedulers supporting it, higher is crawled first.
+	priority int
 }
 
 func NewRequest(url string, respType string, urltag string, method string,
 	postdata string, header http.Header, cookies []*http.Cookie,
 	checkRedirect func(req *http.Request, via []*http.Request) error,
 	meta interface{}) *Request {
-	return &Request{url, respType, method, postdata, urltag, header, cookies, "", checkRedirect, meta}
+	return &Request{url, respType, method, postdata, urltag, header, cookies, "", checkRedirect, meta, 0, 0}
 }
 
 func NewRequestWithProxy(url string, respType string, urltag string, method string,
 	postdata string, header http.Header, cookies []*http.Cookie, proxyHost string,
 	checkRedirect func(req *http.Request, via []*http.Request) error,
 	meta interface{}) *Request {
-	return &Request{url, respType, method, postdata, urltag, header, cookies, proxyHost, checkRedirect, meta}
+	return &Request{url, respType, method, postdata, urltag, header, cookies, proxyHost, checkRedirect, meta, 0, 0}
 }
 
 func NewRequestWithHeaderFile(url string, respType string, headerFile string) *Request {
@@ -130,3 +136,93 @@ func (this *Request) GetRedirectFunc() func(req *http.Request, via []*http.Reque
 func (this *Request) GetMeta() interface{} {
 	return this.meta
 }
+
+func (this *Request) GetDepth() int {
+	return this.depth
+}
+
+// SetDepth sets the number of links followed from a seed request to this one.
+func (this *Request) SetDepth(depth int) *Request {
+	this.depth = depth
+	return this
+}
+
+func (this *Request) GetPriority() int {
+	return this.priority
+}
+
+// SetPriority sets the order of the request in schedulers supporting it, higher is crawled first.
+func (this *Request) SetPriority(priority int) *Request {
+	this.priority = priority
+	return this
+}
+
+// requestJson is the serialized form of Request used by MarshalJSON and UnmarshalJSON.
+type requestJson struct {
+	Url       string          `json:"url"`
+	RespType  string          `json:"resp_type"`
+	Method    string          `json:"method"`
+	Postdata  string          `json:"postdata,omitempty"`
+	Urltag    string          `json:"urltag,omitempty"`
+	Header    http.Header     `json:"header,omitempty"`
+	Cookies   []*http.Cookie  `json:"cookies,omitempty"`
+	ProxyHost string          `json:"proxy_host,omitempty"`
+	Meta      json.RawMessage `json:"meta,omitempty"`
+	Depth     int             `json:"depth,omitempty"`
+	Priority  int             `json:"priority,omitempty"`
+}
+
+// MarshalJSON encodes the request so that it can be persisted by a Scheduler.
+// The redirect function can not be serialized and is dropped,
+// and so is meta when it is not json encodable.
+func (this *Request) MarshalJSON() ([]byte, error) {
+	rj := requestJson{
+		Url:       this.url,
+		RespType:  this.respType,
+		Method:    this.method,
+		Postdata:  this.postdata,
+		Urltag:    this.urltag,
+		Header:    this.header,
+		Cookies:   this.cookies,
+		ProxyHost: this.proxyHost,
+		Depth:     this.depth,
+		Priority:  this.priority,
+	}
+	if this.meta != nil {
+		if meta, err := json.Marshal(this.meta); err == nil {
+			rj.Meta = meta
+		}
+	}
+	return json.Marshal(rj)
+}
+
+// UnmarshalJSON restores a request encoded by MarshalJSON.
+// The meta is restored as generic json value (map[string]interface{}, []interface{}, float64, string or bool).
+func (this *Request) UnmarshalJSON(b []byte) error {
+	var rj requestJson
+	if err := json.Unmarshal(b, &rj); err != nil {
+		return err
+	}
+
+	var meta interface{}
+	if len(rj.Meta) != 0 {
+		if err := json.Unmarshal(rj.Meta, &meta); err != nil {
+			return err
+		}
+	}
+
+	*this = Request{
+		url:       rj.Url,
+		respType:  rj.RespType,
+		method:    rj.Method,
+		postdata:  rj.Postdata,
+		urltag:    rj.Urltag,
+		header:    rj.Header,
+		cookies:   rj.Cookies,
+		proxyHost: rj.ProxyHost,
+		meta:      meta,
+		depth:     rj.Depth,
+		priority:  rj.Priority,
+	}
+	return nil
+}
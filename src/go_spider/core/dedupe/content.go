@@ -0,0 +1,149 @@
+package dedupe
+
+import (
+	"crypto/sha1"
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// The shingleSize is how many characters make one feature of a simhash.
+const shingleSize = 4
+
+// ContentDeduper remembers the fingerprints of page contents to find pages seen before,
+// such as one page mirrored under several urls.
+// With distance 0 contents are duplicates when they are equal apart from whitespace,
+// otherwise when their simhashes differ in at most distance bits.
+type ContentDeduper struct {
+	locker   *sync.Mutex
+	distance int
+
+	exact map[[sha1.Size]byte]string
+
+	// The simhashes are indexed by distance+1 bands of bits: by the pigeonhole principle
+	// two hashes within distance bits are equal in at least one band.
+	hashes []uint64
+	urls   []string
+	bands  []map[uint64][]int
+}
+
+// NewContentDeduper finds contents equal apart from whitespace.
+func NewContentDeduper() *ContentDeduper {
+	return &ContentDeduper{locker: new(sync.Mutex), exact: make(map[[sha1.Size]byte]string)}
+}
+
+// NewSimhashDeduper finds near duplicate contents whose simhashes differ in at most distance bits.
+// A distance of 3 suits pages differing only by a timestamp or a counter.
+func NewSimhashDeduper(distance int) *ContentDeduper {
+	if distance <= 0 {
+		return NewContentDeduper()
+	}
+	if distance > 63 {
+		distance = 63
+	}
+	this := &ContentDeduper{locker: new(sync.Mutex), distance: distance}
+	this.bands = make([]map[uint64][]int, distance+1)
+	for i := range this.bands {
+		this.bands[i] = make(map[uint64][]int)
+	}
+	return this
+}
+
+// Seen reports whether text was seen before and returns the url it was first seen at.
+// Otherwise text is remembered as the content of url.
+func (this *ContentDeduper) Seen(url string, text string) (string, bool) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if this.exact != nil {
+		sum := sha1.Sum([]byte(strings.Join(strings.Fields(text), " ")))
+		if first, ok := this.exact[sum]; ok {
+			return first, true
+		}
+		this.exact[sum] = url
+		return "", false
+	}
+
+	h := Simhash(text)
+	for i, band := range this.bands {
+		for _, n := range band[this.band(h, i)] {
+			if Distance(h, this.hashes[n]) <= this.distance {
+				return this.urls[n], true
+			}
+		}
+	}
+
+	n := len(this.hashes)
+	this.hashes = append(this.hashes, h)
+	this.urls = append(this.urls, url)
+	for i, band := range this.bands {
+		key := this.band(h, i)
+		band[key] = append(band[key], n)
+	}
+	return "", false
+}
+
+// Count returns how many distinct contents are remembered.
+func (this *ContentDeduper) Count() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if this.exact != nil {
+		return len(this.exact)
+	}
+	return len(this.hashes)
+}
+
+// The band returns the i-th band of bits of h, the last band holding the remaining bits.
+func (this *ContentDeduper) band(h uint64, i int) uint64 {
+	width := uint(64 / len(this.bands))
+	start := uint(i) * width
+	if i == len(this.bands)-1 {
+		return h >> start
+	}
+	return (h >> start) & (1<<width - 1)
+}
+
+// Simhash returns the 64 bit simhash of text, whose features are the shingles of
+// shingleSize characters of the lowercased text with whitespace collapsed.
+// So it works for languages not separating words by spaces as well.
+func Simhash(text string) uint64 {
+	runes := []rune(strings.ToLower(strings.Join(strings.FieldsFunc(text, unicode.IsSpace), " ")))
+	if len(runes) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	add := func(feature string) {
+		f := fnv.New64a()
+		f.Write([]byte(feature))
+		h := f.Sum64()
+		for i := uint(0); i < 64; i++ {
+			if h&(1<<i) != 0 {
+				weights[i]++
+			} else {
+				weights[i]--
+			}
+		}
+	}
+	if len(runes) < shingleSize {
+		add(string(runes))
+	}
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		add(string(runes[i : i+shingleSize]))
+	}
+
+	var h uint64
+	for i := uint(0); i < 64; i++ {
+		if weights[i] > 0 {
+			h |= 1 << i
+		}
+	}
+	return h
+}
+
+// Distance returns the hamming distance of two simhashes.
+func Distance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,65 @@
+package dedupe
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	n := NewNormalizer()
+	cases := map[string]string{
+		"http://a/x?b=1&a=2":                         "http://a/x?a=2&b=1",
+		"HTTP://A/x?a=2&b=1#frag":                    "http://a/x?a=2&b=1",
+		"http://a:80/x?a=2&utm_source=s&b=1&gclid=g": "http://a/x?a=2&b=1",
+		"https://a.com:443":                          "https://a.com/",
+		"https://a.com:8443/a/./b/../c/":             "https://a.com:8443/a/c/",
+		"http://a.com/x?q=%E4%B8%AD&a":               "http://a.com/x?a&q=%E4%B8%AD",
+		"mailto:a@b.com":                             "mailto:a@b.com",
+		"http://a.com/a%2Fb/./c":                     "http://a.com/a%2Fb/c",
+		"http://a.com/a/b/c":                         "http://a.com/a/b/c",
+		"http://a.com/%7Euser/x%20y":                 "http://a.com/~user/x%20y",
+		"http://a.com/a%2fb%7e":                      "http://a.com/a%2Fb~",
+	}
+	for in, want := range cases {
+		if got := n.Normalize(in); got != want {
+			t.Errorf("normalize %s : %s, want %s", in, got, want)
+		}
+	}
+
+	n = NewNormalizer().SetStripParams("sid").SetKeepFragment(true).SetSortQuery(false)
+	if got := n.Normalize("http://a/x?b=1&SID=3&utm_source=s#top"); got != "http://a/x?b=1&utm_source=s#top" {
+		t.Error("normalizer options error : " + got)
+	}
+}
+
+func TestContentDeduper(t *testing.T) {
+	d := NewContentDeduper()
+	if _, dup := d.Seen("http://a", "hello  world\n"); dup {
+		t.Error("first content is not duplicate")
+	}
+	if first, dup := d.Seen("http://b", "hello world"); !dup || first != "http://a" {
+		t.Error("exact duplicate error")
+	}
+	if _, dup := d.Seen("http://c", "hello world!"); dup {
+		t.Error("different content is not duplicate")
+	}
+
+	var words []string
+	for i := 0; i < 300; i++ {
+		words = append(words, fmt.Sprintf("word%d", i))
+	}
+	page := strings.Join(words, " ")
+
+	s := NewSimhashDeduper(3)
+	s.Seen("http://a", page+" visited 10:01")
+	if first, dup := s.Seen("http://mirror", page+" visited 10:02"); !dup || first != "http://a" {
+		t.Errorf("near duplicate error, distance %d", Distance(Simhash(page+" visited 10:01"), Simhash(page+" visited 10:02")))
+	}
+	if _, dup := s.Seen("http://other", "a completely different page about something else entirely"); dup {
+		t.Error("different content is not near duplicate")
+	}
+	if s.Count() != 2 {
+		t.Errorf("count error : %d", s.Count())
+	}
+}
@@ -0,0 +1,187 @@
+// Package dedupe finds requests and pages a crawl has already seen.
+// Urls are compared in a canonical form given by Normalizer,
+// and page contents by exact or simhash fingerprints kept by ContentDeduper.
+package dedupe
+
+import (
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultStripParams are the tracking parameters removed by a new Normalizer.
+// A name ending with "*" matches every parameter with that prefix.
+var DefaultStripParams = []string{"utm_*", "gclid", "fbclid", "msclkid"}
+
+// Normalizer gives the canonical form of urls, so that variants of one url are deduplicated.
+// It lowercases the scheme and host, removes default ports, dot segments, the fragment and
+// stripped parameters, and sorts the query by parameter name.
+type Normalizer struct {
+	stripParams  []string
+	keepFragment bool
+	sortQuery    bool
+}
+
+// NewNormalizer strips DefaultStripParams and sorts the query.
+func NewNormalizer() *Normalizer {
+	return &Normalizer{stripParams: DefaultStripParams, sortQuery: true}
+}
+
+// SetStripParams replaces the parameters removed from the query. Parameter names are case insensitive.
+func (this *Normalizer) SetStripParams(params ...string) *Normalizer {
+	this.stripParams = params
+	return this
+}
+
+// SetKeepFragment keeps the fragment, for sites routing pages by it.
+func (this *Normalizer) SetKeepFragment(keep bool) *Normalizer {
+	this.keepFragment = keep
+	return this
+}
+
+// SetSortQuery sets whether the query parameters are sorted by name.
+func (this *Normalizer) SetSortQuery(sortQuery bool) *Normalizer {
+	this.sortQuery = sortQuery
+	return this
+}
+
+// Normalize returns the canonical form of rawurl, or rawurl itself when it can not be parsed.
+func (this *Normalizer) Normalize(rawurl string) string {
+	u, err := url.Parse(strings.TrimSpace(rawurl))
+	if err != nil {
+		return rawurl
+	}
+	if u.Opaque != "" {
+		return u.String()
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	port := u.Port()
+	if port != "" && !(u.Scheme == "http" && port == "80") && !(u.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	u.Host = host
+
+	if u.RawPath != "" {
+		// The path has escapes that differ from the default encoding, such as %2F,
+		// so the escaped path is cleaned to keep "a%2Fb" apart from "a/b".
+		raw := cleanPath(normalizeEscapes(u.RawPath))
+		if p, err := url.PathUnescape(raw); err == nil {
+			u.Path, u.RawPath = p, raw
+		} else {
+			u.Path, u.RawPath = cleanPath(u.Path), ""
+		}
+	} else {
+		u.Path = cleanPath(u.Path)
+	}
+	if u.Path == "" && u.Host != "" {
+		u.Path = "/"
+	}
+	u.RawQuery = this.normalizeQuery(u.RawQuery)
+	if !this.keepFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+	return u.String()
+}
+
+// The normalizeQuery keeps the encoding of the remaining parameters as it is.
+func (this *Normalizer) normalizeQuery(rawquery string) string {
+	type param struct {
+		name string
+		raw  string
+	}
+	var params []param
+	for _, raw := range strings.Split(rawquery, "&") {
+		if raw == "" {
+			continue
+		}
+		name := raw
+		if i := strings.Index(raw, "="); i >= 0 {
+			name = raw[:i]
+		}
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		if this.stripped(name) {
+			continue
+		}
+		params = append(params, param{name, raw})
+	}
+
+	if this.sortQuery {
+		sort.SliceStable(params, func(i, j int) bool {
+			if params[i].name != params[j].name {
+				return params[i].name < params[j].name
+			}
+			return params[i].raw < params[j].raw
+		})
+	}
+
+	raws := make([]string, len(params))
+	for i, p := range params {
+		raws[i] = p.raw
+	}
+	return strings.Join(raws, "&")
+}
+
+func (this *Normalizer) stripped(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range this.stripParams {
+		p = strings.ToLower(p)
+		if strings.HasSuffix(p, "*") {
+			if strings.HasPrefix(name, p[:len(p)-1]) {
+				return true
+			}
+		} else if name == p {
+			return true
+		}
+	}
+	return false
+}
+
+// The normalizeEscapes decodes the escaped unreserved characters of an escaped path, as "%7E" is "~",
+// and uppercases the other escapes, which are kept as they differ from their characters.
+func normalizeEscapes(raw string) string {
+	var b strings.Builder
+	for i := 0; i < len(raw); i++ {
+		if raw[i] != '%' || i+2 >= len(raw) {
+			b.WriteByte(raw[i])
+			continue
+		}
+		c, err := url.PathUnescape(raw[i : i+3])
+		if err != nil {
+			b.WriteByte(raw[i])
+			continue
+		}
+		if unreserved(c[0]) {
+			b.WriteString(c)
+		} else {
+			b.WriteString(strings.ToUpper(raw[i : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+func unreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// The cleanPath removes dot segments and duplicate slashes but keeps a trailing slash.
+func cleanPath(p string) string {
+	if p == "" || p == "/" {
+		return p
+	}
+	cleaned := path.Clean(p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
@@ -1,8 +1,21 @@
 package downloader
 
 import (
+	"bytes"
+	"golang.org/x/net/html/charset"
+	"io/ioutil"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/bitly/go-simplejson"
+)
+
+import (
+	"go_spider/core/common/mlog"
 	"go_spider/core/common/page"
 	"go_spider/core/common/request"
+	"go_spider/core/common/util"
 )
 
 // The Downloader interface.
@@ -11,3 +24,63 @@ import (
 type Downloader interface {
 	Download(req *request.Request) *page.Page
 }
+
+func validResponseType(mtype string) bool {
+	switch mtype {
+	case "html", "json", "jsonp", "text":
+		return true
+	}
+	return false
+}
+
+// The parseBody fills p with body parsed by the response type mtype,
+// so that every Downloader gives the same page for the same content.
+func parseBody(p *page.Page, mtype string, body string) *page.Page {
+	switch mtype {
+	case "html":
+		doc, err := goquery.NewDocumentFromReader(bytes.NewReader([]byte(body)))
+		if err != nil {
+			mlog.LogInst().LogError(err.Error())
+			p.SetStatus(true, err.Error())
+			return p
+		}
+		if body, err = doc.Html(); err != nil {
+			mlog.LogInst().LogError(err.Error())
+			p.SetStatus(true, err.Error())
+			return p
+		}
+		p.SetBodyStr(body).SetHtmlParser(doc).SetStatus(false, "")
+	case "json", "jsonp":
+		if mtype == "jsonp" {
+			body = util.JsonpToJson(body)
+		}
+		r, err := simplejson.NewJson([]byte(body))
+		if err != nil {
+			mlog.LogInst().LogError(body + "\t" + err.Error())
+			p.SetStatus(true, err.Error())
+			return p
+		}
+		p.SetBodyStr(body).SetJson(r).SetStatus(false, "")
+	case "text":
+		p.SetBodyStr(body).SetStatus(false, "")
+	default:
+		mlog.LogInst().LogError("error request type:" + mtype)
+	}
+	return p
+}
+
+// The decodeCharset changes body to utf-8 by the charset of contentType or of the content itself.
+func decodeCharset(contentType string, body []byte) string {
+	destReader, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		mlog.LogInst().LogError(err.Error())
+		return string(body)
+	}
+
+	sorbody, err := ioutil.ReadAll(destReader)
+	if err != nil {
+		mlog.LogInst().LogError(err.Error())
+		return string(body)
+	}
+	return string(sorbody)
+}
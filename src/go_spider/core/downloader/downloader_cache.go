@@ -0,0 +1,168 @@
+package downloader
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+import (
+	"go_spider/core/common/mlog"
+	"go_spider/core/common/page"
+	"go_spider/core/common/request"
+)
+
+// The modes of CacheDownloader.
+const (
+	// CacheRecord downloads every request and stores the response.
+	CacheRecord = iota
+	// CacheReplay only serves stored responses and fails the other requests with ErrmsgNotCached.
+	CacheReplay
+	// CacheRecordMissing serves stored responses and downloads and stores the other requests.
+	CacheRecordMissing
+)
+
+// ErrmsgNotCached is the page error message of a request with no stored response in CacheReplay mode.
+const ErrmsgNotCached = "not cached"
+
+// The CacheDownloader records the responses of another Downloader in a directory and replays them,
+// so that page processors are tested offline against the same pages every time.
+// A response is stored as one json file named by the hash of the method, url and post data.
+// Successful responses and responses with status 400 or above are stored, other failures are not.
+type CacheDownloader struct {
+	dir  string
+	next Downloader
+	mode int
+}
+
+// The cacheEntry is the stored response of a request.
+type cacheEntry struct {
+	Url      string      `json:"url"`
+	Method   string      `json:"method"`
+	Postdata string      `json:"postdata,omitempty"`
+	Status   int         `json:"status"`
+	Header   http.Header `json:"header"`
+	Body     string      `json:"body"`
+	Time     time.Time   `json:"time"`
+}
+
+// NewCacheDownloader stores responses of next in dir in CacheRecordMissing mode.
+// The next may be nil in CacheReplay mode.
+func NewCacheDownloader(dir string, next Downloader) *CacheDownloader {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		panic("cache directory create error : " + err.Error())
+	}
+	return &CacheDownloader{dir: dir, next: next, mode: CacheRecordMissing}
+}
+
+// SetMode sets CacheRecord, CacheReplay or CacheRecordMissing.
+func (this *CacheDownloader) SetMode(mode int) *CacheDownloader {
+	this.mode = mode
+	return this
+}
+
+func (this *CacheDownloader) Download(req *request.Request) *page.Page {
+	if this.mode != CacheRecord {
+		if e := this.load(req); e != nil {
+			return this.replay(req, e)
+		}
+		if this.mode == CacheReplay || this.next == nil {
+			p := page.NewPage(req)
+			p.SetStatus(true, ErrmsgNotCached)
+			return p
+		}
+	}
+
+	p := this.next.Download(req)
+	if (p.IsSucc() && !p.GetSkip()) || p.GetStatusCode() >= 400 {
+		this.store(req, p)
+	}
+	return p
+}
+
+func (this *CacheDownloader) replay(req *request.Request, e *cacheEntry) *page.Page {
+	p := page.NewPage(req)
+	p.SetStatusCode(e.Status).SetHeader(e.Header)
+	p.SetCookies((&http.Response{Header: e.Header}).Cookies())
+
+	if e.Status >= 400 {
+		p.SetBodyStr(e.Body)
+		p.SetStatus(true, "http status "+strconv.Itoa(e.Status))
+		return p
+	}
+
+	// the stored body of a jsonp request is already json
+	mtype := req.GetResponseType()
+	if mtype == "jsonp" {
+		mtype = "json"
+	}
+	return parseBody(p, mtype, e.Body)
+}
+
+func (this *CacheDownloader) load(req *request.Request) *cacheEntry {
+	b, err := ioutil.ReadFile(this.path(req))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			mlog.LogInst().LogError("CacheDownloader read error : " + err.Error())
+		}
+		return nil
+	}
+
+	e := new(cacheEntry)
+	if err = json.Unmarshal(b, e); err != nil {
+		mlog.LogInst().LogError("CacheDownloader decode error : " + err.Error())
+		return nil
+	}
+	return e
+}
+
+// The store writes a temporary file first, so a crash never leaves a partial entry.
+func (this *CacheDownloader) store(req *request.Request, p *page.Page) {
+	e := &cacheEntry{
+		Url:      req.GetUrl(),
+		Method:   req.GetMethod(),
+		Postdata: req.GetPostdata(),
+		Status:   p.GetStatusCode(),
+		Header:   p.GetHeader(),
+		Body:     p.GetBodyStr(),
+		Time:     time.Now(),
+	}
+	b, err := json.MarshalIndent(e, "", "\t")
+	if err != nil {
+		mlog.LogInst().LogError("CacheDownloader encode error : " + err.Error())
+		return
+	}
+
+	path := this.path(req)
+	f, err := ioutil.TempFile(this.dir, "tmp")
+	if err != nil {
+		mlog.LogInst().LogError("CacheDownloader write error : " + err.Error())
+		return
+	}
+	_, err = f.Write(b)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		mlog.LogInst().LogError("CacheDownloader write error : " + err.Error())
+	}
+}
+
+func (this *CacheDownloader) path(req *request.Request) string {
+	method := req.GetMethod()
+	if method == "" {
+		method = "GET"
+	}
+	sum := sha1.Sum([]byte(method + " " + req.GetUrl() + "\n" + req.GetPostdata()))
+	return filepath.Join(this.dir, hex.EncodeToString(sum[:])+".json")
+}
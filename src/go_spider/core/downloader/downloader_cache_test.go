@@ -0,0 +1,105 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+import (
+	"go_spider/core/common/page"
+	"go_spider/core/common/request"
+)
+
+func TestFileDownloader(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "file_downloader")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "a.html"), []byte("<html><body><p>saved</p></body></html>"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "a.json"), []byte(`{"name": "saved"}`), 0644)
+
+	d := NewFileDownloader(dir)
+	p := d.Download(request.NewRequest("file://"+filepath.ToSlash(filepath.Join(dir, "a.html")), "html", "", "GET", "", nil, nil, nil, nil))
+	if !p.IsSucc() || p.GetHtmlParser().Find("p").Text() != "saved" {
+		t.Error("file url error : " + p.Errormsg())
+	}
+	p = d.Download(request.NewRequest("a.json", "json", "", "GET", "", nil, nil, nil, nil))
+	if !p.IsSucc() || p.GetJson().Get("name").MustString() != "saved" {
+		t.Error("relative path error : " + p.Errormsg())
+	}
+	p = d.Download(request.NewRequest("missing.html", "html", "", "GET", "", nil, nil, nil, nil))
+	if p.IsSucc() {
+		t.Error("missing file should fail")
+	}
+}
+
+func TestCacheDownloader(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "sid", Value: "1"})
+		w.Write([]byte("<html><body><p>" + r.URL.Path + "</p></body></html>"))
+	}))
+	defer ts.Close()
+
+	dir, _ := ioutil.TempDir("", "cache_downloader")
+	defer os.RemoveAll(dir)
+
+	get := func(d Downloader, path string) *page.Page {
+		return d.Download(request.NewRequest(ts.URL+path, "html", "", "GET", "", nil, nil, nil, nil))
+	}
+
+	d := NewCacheDownloader(dir, NewHttpDownloader())
+	get(d, "/a")
+	get(d, "/missing")
+	p := get(d, "/a")
+	if hits != 2 {
+		t.Errorf("stored response should be replayed : %d hits", hits)
+	}
+	if !p.IsSucc() || p.GetHtmlParser().Find("p").Text() != "/a" || len(p.GetCookies()) != 1 {
+		t.Error("replay error : " + p.Errormsg())
+	}
+
+	offline := NewCacheDownloader(dir, nil).SetMode(CacheReplay)
+	p = get(offline, "/missing")
+	if p.IsSucc() || p.GetStatusCode() != 404 {
+		t.Errorf("replayed status error : %d", p.GetStatusCode())
+	}
+	p = get(offline, "/b")
+	if p.IsSucc() || p.Errormsg() != ErrmsgNotCached {
+		t.Error("not cached error : " + p.Errormsg())
+	}
+
+	get(NewCacheDownloader(dir, NewHttpDownloader()).SetMode(CacheRecord), "/a")
+	if hits != 3 {
+		t.Errorf("record mode should download : %d hits", hits)
+	}
+}
+
+func TestMuxDownloader(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "mux_downloader")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "a.txt"), []byte("file"), 0644)
+
+	tagged := NewCacheDownloader(dir, nil).SetMode(CacheReplay)
+	d := NewMuxDownloader(nil).HandleScheme("file", NewFileDownloader(dir)).HandleUrlTag("offline", tagged)
+
+	p := d.Download(request.NewRequest("FILE:a.txt", "text", "", "GET", "", nil, nil, nil, nil))
+	if p.GetBodyStr() != "file" {
+		t.Error("scheme route error : " + p.Errormsg())
+	}
+	p = d.Download(request.NewRequest("file:a.txt", "text", "offline", "GET", "", nil, nil, nil, nil))
+	if p.Errormsg() != ErrmsgNotCached {
+		t.Error("url tag route error : " + p.Errormsg())
+	}
+	p = d.Download(request.NewRequest("http://a.com", "text", "", "GET", "", nil, nil, nil, nil))
+	if p.IsSucc() {
+		t.Error("unrouted request should fail")
+	}
+}
@@ -0,0 +1,81 @@
+package downloader
+
+import (
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+import (
+	"go_spider/core/common/mlog"
+	"go_spider/core/common/page"
+	"go_spider/core/common/request"
+)
+
+// The FileDownloader reads pages saved on the local disk, to process them again without the network.
+// The url of a request is a "file://" url or a plain path, and a relative path is resolved in the root directory.
+// The charset is detected as for http, with the content type guessed by the file extension.
+type FileDownloader struct {
+	root string
+}
+
+func NewFileDownloader(root string) *FileDownloader {
+	return &FileDownloader{root: root}
+}
+
+func (this *FileDownloader) Download(req *request.Request) *page.Page {
+	p := page.NewPage(req)
+	mtype := req.GetResponseType()
+	if !validResponseType(mtype) {
+		mlog.LogInst().LogError("error request type:" + mtype)
+		return p
+	}
+
+	path, err := this.path(req.GetUrl())
+	if err != nil {
+		mlog.LogInst().LogError(err.Error())
+		p.SetStatus(true, err.Error())
+		return p
+	}
+	body, err := ioutil.ReadFile(path)
+	if err != nil {
+		mlog.LogInst().LogError(err.Error())
+		p.SetStatus(true, err.Error())
+		return p
+	}
+
+	header := make(http.Header)
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	p.SetStatusCode(http.StatusOK).SetHeader(header)
+	return parseBody(p, mtype, decodeCharset(contentType, body))
+}
+
+func (this *FileDownloader) path(rawurl string) (string, error) {
+	path := rawurl
+	if strings.HasPrefix(strings.ToLower(rawurl), "file:") {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return "", err
+		}
+		path = u.Path
+		// "file://dir/a.html" is the relative path "dir/a.html"
+		if u.Host != "" && u.Host != "localhost" {
+			path = u.Host + u.Path
+		}
+		if u.Opaque != "" {
+			path = u.Opaque
+		}
+	}
+
+	path = filepath.FromSlash(path)
+	if !filepath.IsAbs(path) && this.root != "" {
+		path = filepath.Join(this.root, path)
+	}
+	return path, nil
+}
@@ -1,26 +1,33 @@
 package downloader
 
 import (
-	"bytes"
-	"fmt"
-	"golang.org/x/net/html/charset"
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
 	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/cookiejar"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 import (
-	"github.com/PuerkitoBio/goquery"
-	"github.com/bitly/go-simplejson"
+	"golang.org/x/net/proxy"
+	"golang.org/x/net/publicsuffix"
 )
 
 import (
 	"go_spider/core/common/mlog"
 	"go_spider/core/common/page"
 	"go_spider/core/common/request"
-	"go_spider/core/common/util"
 )
 
 // The HttpDownloader download page by package net/http.
@@ -29,103 +36,160 @@ import (
 // The "jsonp" content is modified to json.
 // The "text" content will save body plain text only.
 // The page result is saved in Page.
+//
+// One http.Transport is shared by all requests so connections are reused.
+// Responses with status 400 or above are failed pages that still hold the body.
+// Gzip and deflate bodies are decoded, and cookies are kept in a jar shared by the requests of the downloader.
 type HttpDownloader struct {
+	transport   *http.Transport
+	jar         http.CookieJar
+	timeout     time.Duration
+	maxBodySize int64
+	header      http.Header
+
+	// The conditional enables ETag/If-Modified-Since requests, validators holds them per url.
+	conditional     bool
+	validatorLocker *sync.Mutex
+	validators      map[string]validator
+
+	proxyProvider ProxyProvider
+	// The socksTransports holds one transport per socks5 proxy, so pooled connections are not mixed.
+	socksLocker     *sync.Mutex
+	socksTransports map[string]*http.Transport
 }
 
-func NewHttpDownloader() *HttpDownloader {
-	return &HttpDownloader{}
+type validator struct {
+	etag         string
+	lastModified string
 }
 
-func (this *HttpDownloader) Download(req *request.Request) *page.Page {
-	var mtype string
-	var p = page.NewPage(req)
-	mtype = req.GetResponseType()
-	switch mtype {
-	case "html":
-		return this.downloadHtml(p, req)
-	case "json":
-		return this.downloadJson(p, req)
-	case "text":
-		return this.downloadText(p, req)
-	default:
-		mlog.LogInst().LogError("error request type:" + mtype)
+type proxyKey struct{}
+
+// ErrmsgReadBody starts the page error message of a response whose body could not be read,
+// such as a connection reset or a truncated gzip body. The RetryPolicy retries it as a connection error.
+const ErrmsgReadBody = "read body error"
+
+func NewHttpDownloader() *HttpDownloader {
+	this := &HttpDownloader{
+		timeout:         30 * time.Second,
+		maxBodySize:     10 << 20,
+		validatorLocker: new(sync.Mutex),
+		validators:      make(map[string]validator),
+		socksLocker:     new(sync.Mutex),
+		socksTransports: make(map[string]*http.Transport),
+	}
+	this.transport = &http.Transport{
+		Proxy: proxyFromRequest,
+		DialContext: (&net.Dialer{
+			Timeout:   10 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		MaxIdleConnsPerHost:   8,
+		IdleConnTimeout:       90 * time.Second,
+		// bodies are decoded by readBody so deflate is supported as well as gzip
+		DisableCompression: true,
 	}
+	this.jar, _ = cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	return this
+}
 
-	return p
+// SetTimeout sets the time limit of a whole request including reading the body. 0 means no limit.
+func (this *HttpDownloader) SetTimeout(d time.Duration) *HttpDownloader {
+	this.timeout = d
+	return this
 }
 
-func (this *HttpDownloader) downloadHtml(p *page.Page, req *request.Request) *page.Page {
-	var err error
-	p, destbody := this.downloadFile(p, req)
-	fmt.Printf("Destbody %v \r\n", destbody)
+// SetConnectTimeout sets the time limit of dialing a connection.
+func (this *HttpDownloader) SetConnectTimeout(d time.Duration) *HttpDownloader {
+	this.transport.DialContext = (&net.Dialer{Timeout: d, KeepAlive: 30 * time.Second}).DialContext
+	return this
+}
 
-	if !p.IsSucc() {
-		fmt.Print("Page error \r\n")
-		return p
-	}
+// SetResponseHeaderTimeout sets the time limit of waiting for the response header after the request is sent.
+func (this *HttpDownloader) SetResponseHeaderTimeout(d time.Duration) *HttpDownloader {
+	this.transport.ResponseHeaderTimeout = d
+	return this
+}
 
-	bodyReader := bytes.NewReader([]byte(destbody))
-	var doc *goquery.Document
-	if doc, err = goquery.NewDocumentFromReader(bodyReader); err != nil {
-		mlog.LogInst().LogError(err.Error())
-		p.SetStatus(true, err.Error())
-		return p
-	}
+// SetMaxBodySize sets the largest decoded body accepted, bigger pages fail. 0 means no limit.
+func (this *HttpDownloader) SetMaxBodySize(n int64) *HttpDownloader {
+	this.maxBodySize = n
+	return this
+}
 
-	var body string
-	if body, err = doc.Html(); err != nil {
-		mlog.LogInst().LogError(err.Error())
-		p.SetStatus(true, err.Error())
-		return p
-	}
+// SetCookieJar sets the jar holding the cookies of the task. nil disables cookies.
+func (this *HttpDownloader) SetCookieJar(jar http.CookieJar) *HttpDownloader {
+	this.jar = jar
+	return this
+}
 
-	p.SetBodyStr(body).SetHtmlParser(doc).SetStatus(false, "")
+func (this *HttpDownloader) GetCookieJar() http.CookieJar {
+	return this.jar
+}
 
-	return p
+// SetConditional enables conditional requests. The ETag and Last-Modified of every page are kept,
+// and a page requested again that is answered with 304 is returned succeeded, without body and skipped.
+func (this *HttpDownloader) SetConditional(conditional bool) *HttpDownloader {
+	this.conditional = conditional
+	return this
 }
 
-func (this *HttpDownloader) downloadJson(p *page.Page, req *request.Request) *page.Page {
-	var err error
-	p, destbody := this.downloadFile(p, req)
-	fmt.Printf("Destbody %v \r\n", destbody)
+// SetHeader sets the headers sent with every request, unless the request sets them itself.
+func (this *HttpDownloader) SetHeader(header http.Header) *HttpDownloader {
+	this.header = header
+	return this
+}
 
-	if !p.IsSucc() {
-		fmt.Print("Page error \r\n")
-		return p
-	}
+// SetProxyProvider sets where the proxy of each request without its own proxy host comes from.
+func (this *HttpDownloader) SetProxyProvider(provider ProxyProvider) *HttpDownloader {
+	this.proxyProvider = provider
+	return this
+}
 
-	var body []byte
-	body = []byte(destbody)
-	mtype := req.GetResponseType()
-	if mtype == "jsonp" {
-		tmpstr := util.JsonpToJson(destbody)
-		body = []byte(tmpstr)
-	}
+// GetTransport returns the transport shared by all requests, for example to set TLS options.
+func (this *HttpDownloader) GetTransport() *http.Transport {
+	return this.transport
+}
 
-	var r *simplejson.Json
-	if r, err = simplejson.NewJson(body); err != nil {
-		mlog.LogInst().LogError(string(body) + "\t" + err.Error())
-		p.SetStatus(true, err.Error())
-		return p
+func (this *HttpDownloader) Download(req *request.Request) *page.Page {
+	var proxyUrl *url.URL
+	if len(req.GetProxyHost()) == 0 && this.proxyProvider != nil {
+		proxyUrl = this.proxyProvider.Get()
 	}
 
-	p.SetBodyStr(string(body)).SetJson(r).SetStatus(false, "")
+	p := this.download(page.NewPage(req), req, proxyUrl)
 
+	if proxyUrl != nil {
+		if err := this.proxyProvider.Report(proxyUrl, p); err != nil {
+			mlog.LogInst().LogError(req.GetUrl() + "\t" + err.Error())
+		}
+	}
 	return p
 }
 
-func (this *HttpDownloader) downloadText(p *page.Page, req *request.Request) *page.Page {
-	p, destbody := this.downloadFile(p, req)
-	if !p.IsSucc() {
+func (this *HttpDownloader) download(p *page.Page, req *request.Request, proxyUrl *url.URL) *page.Page {
+	mtype := req.GetResponseType()
+	if !validResponseType(mtype) {
+		mlog.LogInst().LogError("error request type:" + mtype)
 		return p
 	}
 
-	p.SetBodyStr(destbody).SetStatus(false, "")
-	return p
+	p, destbody := this.downloadFile(p, req, proxyUrl)
+	if !p.IsSucc() || p.GetSkip() {
+		return p
+	}
+	return parseBody(p, mtype, destbody)
 }
 
-func (this *HttpDownloader) downloadFile(p *page.Page, req *request.Request) (*page.Page, string) {
-	var err error
+// The downloadWithProxy downloads rawurl as text through proxyUrl, used to check proxies.
+func (this *HttpDownloader) downloadWithProxy(rawurl string, proxyUrl *url.URL) *page.Page {
+	req := request.NewRequest(rawurl, "text", "", "GET", "", nil, nil, nil, nil)
+	return this.download(page.NewPage(req), req, proxyUrl)
+}
+
+func (this *HttpDownloader) downloadFile(p *page.Page, req *request.Request, proxyUrl *url.URL) (*page.Page, string) {
 	var urlstr string
 	if urlstr = req.GetUrl(); len(urlstr) == 0 {
 		mlog.LogInst().LogError("url is empty")
@@ -133,41 +197,61 @@ func (this *HttpDownloader) downloadFile(p *page.Page, req *request.Request) (*p
 		return p, ""
 	}
 
-	var resp *http.Response
-
-	if proxystr := req.GetProxyHost(); len(proxystr) != 0 {
-		fmt.Print("HttpProxy Enter ", proxystr, "\n")
-		resp, err = connectByHttpProxy(p, req)
-	} else {
-		fmt.Print("Http Normal Enter \n", proxystr, "\n")
-		resp, err = connectByHttp(p, req)
-	}
-
+	resp, err := this.connect(req, proxyUrl)
 	if err != nil {
+		mlog.LogInst().LogError(err.Error())
+		p.SetStatus(true, err.Error())
 		return p, ""
 	}
+	defer resp.Body.Close()
 
-	b, _ := ioutil.ReadAll(resp.Body)
-	fmt.Printf("Resp body %v \r\n", string(b))
-
+	p.SetStatusCode(resp.StatusCode)
 	p.SetHeader(resp.Header)
 	p.SetCookies(resp.Cookies())
 
-	bodyStr := this.changeCharsetEncodingAuto(resp.Header.Get("Content-Type"), resp.Body)
-	fmt.Printf("utf-8 body %v \r\n", bodyStr)
+	if resp.StatusCode == http.StatusNotModified && this.conditional {
+		// the page is unchanged, nothing is processed
+		p.SetSkip(true)
+		return p, ""
+	}
 
-	defer resp.Body.Close()
+	body, err := this.readBody(resp)
+	if err != nil {
+		mlog.LogInst().LogError(urlstr + "\t" + err.Error())
+		p.SetStatus(true, ErrmsgReadBody+" : "+err.Error())
+		return p, ""
+	}
+	bodyStr := this.changeCharsetEncodingAuto(resp.Header.Get("Content-Type"), body)
 
+	if resp.StatusCode >= 400 {
+		p.SetBodyStr(bodyStr)
+		p.SetStatus(true, "http status "+strconv.Itoa(resp.StatusCode))
+		return p, bodyStr
+	}
+
+	this.saveValidator(req, resp)
 	return p, bodyStr
 }
 
-func connectByHttp(p *page.Page, req *request.Request) (*http.Response, error) {
-	client := &http.Client{CheckRedirect: req.GetRedirectFunc()}
-
+// The connect sends req through the shared transport, by proxyUrl or the proxy host of req if any.
+func (this *HttpDownloader) connect(req *request.Request, proxyUrl *url.URL) (*http.Response, error) {
 	httpreq, err := http.NewRequest(req.GetMethod(), req.GetUrl(), strings.NewReader(req.GetPostdata()))
+	if err != nil {
+		return nil, err
+	}
 
 	if header := req.GetHeader(); header != nil {
-		httpreq.Header = req.GetHeader()
+		for key, values := range header {
+			httpreq.Header[key] = append([]string(nil), values...)
+		}
+	}
+	for key, values := range this.header {
+		if _, ok := httpreq.Header[http.CanonicalHeaderKey(key)]; !ok {
+			httpreq.Header[http.CanonicalHeaderKey(key)] = append([]string(nil), values...)
+		}
+	}
+	if httpreq.Header.Get("Accept-Encoding") == "" {
+		httpreq.Header.Set("Accept-Encoding", "gzip, deflate")
 	}
 
 	if cookies := req.GetCookies(); cookies != nil {
@@ -176,57 +260,151 @@ func connectByHttp(p *page.Page, req *request.Request) (*http.Response, error) {
 		}
 	}
 
-	var resp *http.Response
-	if resp, err = client.Do(httpreq); err != nil {
-		if e, ok := err.(*url.Error); ok && e.Err != nil && e.Err.Error() == "normal" {
-			// normal
-		} else {
-			mlog.LogInst().LogError(err.Error())
-			p.SetStatus(true, err.Error())
-			fmt.Printf("client do error %v \r\n", err)
+	this.addValidator(req, httpreq)
+
+	if proxystr := req.GetProxyHost(); len(proxystr) != 0 {
+		if proxyUrl, err = url.Parse(proxystr); err != nil {
 			return nil, err
 		}
 	}
-	return resp, nil
-}
 
-// choose a proxy server to excute http GET/method to download
-func connectByHttpProxy(p *page.Page, req *request.Request) (*http.Response, error) {
-	request, _ := http.NewRequest("GET", req.GetUrl(), nil)
-	proxy, err := url.Parse(req.GetProxyHost())
-	if err != nil {
-		return nil, err
+	transport := this.transport
+	if proxyUrl != nil && proxyUrl.Scheme == "socks5" {
+		if transport, err = this.socksTransport(proxyUrl); err != nil {
+			return nil, err
+		}
+	} else if proxyUrl != nil {
+		httpreq = httpreq.WithContext(context.WithValue(httpreq.Context(), proxyKey{}, proxyUrl))
 	}
 
 	client := &http.Client{
-		Transport: &http.Transport{
-			Proxy: http.ProxyURL(proxy),
-		},
+		Transport:     transport,
+		Jar:           this.jar,
+		Timeout:       this.timeout,
+		CheckRedirect: redirectFunc(req.GetRedirectFunc()),
+	}
+	return client.Do(httpreq)
+}
+
+// The socksTransport returns the transport dialing through socks5 proxy proxyUrl.
+func (this *HttpDownloader) socksTransport(proxyUrl *url.URL) (*http.Transport, error) {
+	this.socksLocker.Lock()
+	defer this.socksLocker.Unlock()
+
+	key := proxyUrl.String()
+	if t, ok := this.socksTransports[key]; ok {
+		return t, nil
 	}
 
-	resp, err := client.Do(request)
+	dialer, err := proxy.FromURL(proxyUrl, &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second})
 	if err != nil {
 		return nil, err
 	}
+	t := this.transport.Clone()
+	t.Proxy = nil
+	t.DialContext = func(ctx context.Context, network string, addr string) (net.Conn, error) {
+		return dialer.Dial(network, addr)
+	}
+	this.socksTransports[key] = t
+	return t, nil
+}
+
+// The redirectFunc adapts the redirect function of Request:
+// its error "normal" keeps the last response as the result instead of failing.
+func redirectFunc(f func(req *http.Request, via []*http.Request) error) func(req *http.Request, via []*http.Request) error {
+	if f == nil {
+		return nil
+	}
+	return func(req *http.Request, via []*http.Request) error {
+		err := f(req, via)
+		if err != nil && err.Error() == "normal" {
+			return http.ErrUseLastResponse
+		}
+		return err
+	}
+}
 
-	return resp, nil
+// The proxyFromRequest chooses the proxy server saved in the request context by connect.
+func proxyFromRequest(req *http.Request) (*url.URL, error) {
+	if proxy, ok := req.Context().Value(proxyKey{}).(*url.URL); ok {
+		return proxy, nil
+	}
+	return nil, nil
 }
 
-// Charset auto determine. Use golang.org/x/net/html/charset. Get page body and change it to utf-8
-func (this *HttpDownloader) changeCharsetEncodingAuto(contentTypeStr string, sor io.ReadCloser) string {
-	var err error
-	destReader, err := charset.NewReader(sor, contentTypeStr)
+// The readBody decodes the gzip or deflate body and reads at most maxBodySize bytes of it.
+func (this *HttpDownloader) readBody(resp *http.Response) ([]byte, error) {
+	var r io.Reader = resp.Body
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		gr, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gr.Close()
+		r = gr
+	case "deflate":
+		// deflate is zlib wrapped by the standard, but some servers send raw deflate
+		br := bufio.NewReader(resp.Body)
+		if head, err := br.Peek(2); err == nil && head[0]&0x0f == 8 && (int(head[0])<<8|int(head[1]))%31 == 0 {
+			zr, err := zlib.NewReader(br)
+			if err != nil {
+				return nil, err
+			}
+			defer zr.Close()
+			r = zr
+		} else {
+			fr := flate.NewReader(br)
+			defer fr.Close()
+			r = fr
+		}
+	}
 
+	if this.maxBodySize <= 0 {
+		return ioutil.ReadAll(r)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r, this.maxBodySize+1))
 	if err != nil {
-		mlog.LogInst().LogError(err.Error())
-		destReader = sor
+		return nil, err
+	}
+	if int64(len(body)) > this.maxBodySize {
+		return nil, errors.New("body is larger than " + strconv.FormatInt(this.maxBodySize, 10) + " bytes")
 	}
+	return body, nil
+}
 
-	var sorbody []byte
-	if sorbody, err = ioutil.ReadAll(destReader); err != nil {
-		mlog.LogInst().LogError(err.Error())
+func (this *HttpDownloader) addValidator(req *request.Request, httpreq *http.Request) {
+	if !this.conditional || req.GetMethod() != "GET" {
+		return
+	}
+	this.validatorLocker.Lock()
+	v, ok := this.validators[req.GetUrl()]
+	this.validatorLocker.Unlock()
+	if !ok {
+		return
+	}
+	if v.etag != "" && httpreq.Header.Get("If-None-Match") == "" {
+		httpreq.Header.Set("If-None-Match", v.etag)
+	}
+	if v.lastModified != "" && httpreq.Header.Get("If-Modified-Since") == "" {
+		httpreq.Header.Set("If-Modified-Since", v.lastModified)
 	}
+}
+
+func (this *HttpDownloader) saveValidator(req *request.Request, resp *http.Response) {
+	if !this.conditional || req.GetMethod() != "GET" {
+		return
+	}
+	v := validator{etag: resp.Header.Get("ETag"), lastModified: resp.Header.Get("Last-Modified")}
+	if v.etag == "" && v.lastModified == "" {
+		return
+	}
+	this.validatorLocker.Lock()
+	this.validators[req.GetUrl()] = v
+	this.validatorLocker.Unlock()
+}
 
-	bodystr := string(sorbody)
-	return bodystr
+// Charset auto determine. Use golang.org/x/net/html/charset. Get page body and change it to utf-8
+func (this *HttpDownloader) changeCharsetEncodingAuto(contentTypeStr string, body []byte) string {
+	return decodeCharset(contentTypeStr, body)
 }
@@ -0,0 +1,68 @@
+package downloader
+
+import (
+	"net/url"
+	"strings"
+)
+
+import (
+	"go_spider/core/common/mlog"
+	"go_spider/core/common/page"
+	"go_spider/core/common/request"
+)
+
+// The MuxDownloader routes each request to a Downloader chosen by its url tag, then by its url scheme,
+// and to the default Downloader otherwise.
+// For example "file" urls can go to a FileDownloader while the others go to a HttpDownloader.
+type MuxDownloader struct {
+	def     Downloader
+	schemes map[string]Downloader
+	tags    map[string]Downloader
+}
+
+// NewMuxDownloader routes requests matching no rule to def, which may be nil.
+func NewMuxDownloader(def Downloader) *MuxDownloader {
+	return &MuxDownloader{
+		def:     def,
+		schemes: make(map[string]Downloader),
+		tags:    make(map[string]Downloader),
+	}
+}
+
+// HandleScheme routes the urls of scheme, such as "file", to d. The scheme "" matches plain paths.
+func (this *MuxDownloader) HandleScheme(scheme string, d Downloader) *MuxDownloader {
+	this.schemes[strings.ToLower(scheme)] = d
+	return this
+}
+
+// HandleUrlTag routes the requests with url tag to d.
+func (this *MuxDownloader) HandleUrlTag(tag string, d Downloader) *MuxDownloader {
+	this.tags[tag] = d
+	return this
+}
+
+func (this *MuxDownloader) Download(req *request.Request) *page.Page {
+	if d := this.route(req); d != nil {
+		return d.Download(req)
+	}
+
+	mlog.LogInst().LogError("no downloader for " + req.GetUrl())
+	p := page.NewPage(req)
+	p.SetStatus(true, "no downloader")
+	return p
+}
+
+func (this *MuxDownloader) route(req *request.Request) Downloader {
+	if d, ok := this.tags[req.GetUrlTag()]; ok && req.GetUrlTag() != "" {
+		return d
+	}
+
+	scheme := ""
+	if u, err := url.Parse(req.GetUrl()); err == nil {
+		scheme = strings.ToLower(u.Scheme)
+	}
+	if d, ok := this.schemes[scheme]; ok {
+		return d
+	}
+	return this.def
+}
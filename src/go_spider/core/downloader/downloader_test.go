@@ -1,8 +1,15 @@
 package downloader
 
 import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 import (
@@ -62,3 +69,127 @@ func TestCharSetChange(t *testing.T) {
 	body := p.GetBodyStr()
 	fmt.Println(body)
 }
+
+func TestHttpDownloaderEncoding(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		switch r.URL.Path {
+		case "/gzip":
+			gw := gzip.NewWriter(&buf)
+			gw.Write([]byte("gzip body"))
+			gw.Close()
+			w.Header().Set("Content-Encoding", "gzip")
+		case "/deflate":
+			fw, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+			fw.Write([]byte("deflate body"))
+			fw.Close()
+			w.Header().Set("Content-Encoding", "deflate")
+		case "/big":
+			buf.WriteString(strings.Repeat("x", 100))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			buf.WriteString("missing")
+		}
+		w.Write(buf.Bytes())
+	}))
+	defer ts.Close()
+
+	dl := NewHttpDownloader().SetMaxBodySize(50)
+	for path, body := range map[string]string{"/gzip": "gzip body", "/deflate": "deflate body"} {
+		p := dl.Download(request.NewRequest(ts.URL+path, "text", "", "GET", "", nil, nil, nil, nil))
+		if !p.IsSucc() || p.GetBodyStr() != body {
+			t.Errorf("%s decode error : %s %s", path, p.Errormsg(), p.GetBodyStr())
+		}
+	}
+
+	p := dl.Download(request.NewRequest(ts.URL+"/big", "text", "", "GET", "", nil, nil, nil, nil))
+	if p.IsSucc() {
+		t.Error("body larger than max body size should fail")
+	}
+	fmt.Println(p.Errormsg())
+
+	p = dl.Download(request.NewRequest(ts.URL+"/missing", "text", "", "GET", "", nil, nil, nil, nil))
+	if p.IsSucc() || p.GetStatusCode() != 404 || p.GetBodyStr() != "missing" {
+		t.Error("404 should fail with body")
+	}
+}
+
+func TestHttpDownloaderConditionalAndCookies(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "1"})
+			return
+		}
+		if c, err := r.Cookie("session"); err != nil || c.Value != "1" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("content"))
+	}))
+	defer ts.Close()
+
+	dl := NewHttpDownloader().SetConditional(true)
+	dl.Download(request.NewRequest(ts.URL+"/login", "text", "", "GET", "", nil, nil, nil, nil))
+
+	p := dl.Download(request.NewRequest(ts.URL+"/page", "text", "", "GET", "", nil, nil, nil, nil))
+	if !p.IsSucc() || p.GetBodyStr() != "content" {
+		t.Errorf("cookie jar error : %d", p.GetStatusCode())
+	}
+	p = dl.Download(request.NewRequest(ts.URL+"/page", "text", "", "GET", "", nil, nil, nil, nil))
+	if !p.IsSucc() || !p.GetSkip() || p.GetStatusCode() != http.StatusNotModified {
+		t.Error("an unchanged page should be skipped")
+	}
+}
+
+func TestHttpDownloaderReadBodyError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the connection is closed before the announced body is sent
+		w.Header().Set("Content-Length", "100")
+		w.Write([]byte("truncated"))
+	}))
+	defer ts.Close()
+
+	p := NewHttpDownloader().Download(request.NewRequest(ts.URL, "text", "", "GET", "", nil, nil, nil, nil))
+	if p.IsSucc() || p.GetStatusCode() != 200 || !strings.HasPrefix(p.Errormsg(), ErrmsgReadBody) {
+		t.Errorf("read body error : %d %s", p.GetStatusCode(), p.Errormsg())
+	}
+	if _, retry := NewRetryPolicy().Backoff(p, 1); !retry {
+		t.Error("a page failed while reading the body should be retried")
+	}
+	if _, retry := NoRetryPolicy().Backoff(p, 1); retry {
+		t.Error("a page should not be retried without attempts left")
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := NewRetryPolicy()
+	policy.BaseDelay = 100 * time.Millisecond
+
+	p := page.NewPage(request.NewRequest("http://a.com", "text", "", "GET", "", nil, nil, nil, nil))
+	p.SetStatus(true, "connection refused")
+	if d, ok := policy.Backoff(p, 1); !ok || d < 100*time.Millisecond || d > 150*time.Millisecond {
+		t.Errorf("first backoff error : %v", d)
+	}
+	if d, ok := policy.Backoff(p, 2); !ok || d < 200*time.Millisecond || d > 300*time.Millisecond {
+		t.Errorf("second backoff error : %v", d)
+	}
+	if _, ok := policy.Backoff(p, 3); ok {
+		t.Error("max attempts error")
+	}
+
+	p.SetStatusCode(404)
+	if _, ok := policy.Backoff(p, 1); ok {
+		t.Error("404 should not be retried")
+	}
+
+	p.SetStatusCode(503)
+	p.SetHeader(http.Header{"Retry-After": []string{"2"}})
+	if d, ok := policy.Backoff(p, 1); !ok || d != 2*time.Second {
+		t.Errorf("Retry-After error : %v", d)
+	}
+}
@@ -0,0 +1,92 @@
+package downloader
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+import (
+	"go_spider/core/common/request"
+)
+
+func TestProxyPoolSelection(t *testing.T) {
+	pool := NewProxyPool()
+	pool.AddProxy("http://a:1", 1)
+	pool.AddProxy("http://b:1", 1)
+	if err := pool.AddProxy("ftp://c:1", 1); err == nil {
+		t.Error("ftp proxy should be rejected")
+	}
+	if a, b, c := pool.Get(), pool.Get(), pool.Get(); a.Host != "a:1" || b.Host != "b:1" || c.Host != "a:1" {
+		t.Error("round-robin error")
+	}
+
+	pool = NewProxyPool().SetWeighted(true)
+	pool.AddProxy("http://a:1", 3)
+	pool.AddProxy("socks5://b:1", 1)
+	count := map[string]int{}
+	for i := 0; i < 8; i++ {
+		count[pool.Get().Host]++
+	}
+	if count["a:1"] != 6 || count["b:1"] != 2 {
+		t.Errorf("weighted error : %v", count)
+	}
+}
+
+func TestProxyPoolEviction(t *testing.T) {
+	// the servers act as http proxies answering every request themselves
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok "+r.URL.String())
+	}))
+	defer good.Close()
+	captcha := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "please fill the captcha")
+	}))
+	defer captcha.Close()
+	dead := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	dead.Close()
+
+	pool := NewProxyPool().SetMaxFailures(2).SetCooldown(100 * time.Millisecond)
+	pool.AddProxy(good.URL, 1)
+	pool.AddProxy(captcha.URL, 1)
+	pool.AddProxy(dead.URL, 1)
+	pool.AddBanPattern("captcha")
+
+	dl := NewHttpDownloader().SetProxyProvider(pool)
+	req := request.NewRequest("http://example.com/page", "text", "", "GET", "", nil, nil, nil, nil)
+
+	succ, banned, failed := 0, 0, 0
+	for i := 0; i < 4; i++ {
+		p := dl.Download(req)
+		if p.IsSucc() && p.GetBodyStr() == "ok http://example.com/page" {
+			succ++
+		} else if p.Errormsg() == ErrmsgProxyBanned {
+			banned++
+		} else if !p.IsSucc() {
+			failed++
+		}
+	}
+	if succ != 2 || banned != 1 || failed != 1 {
+		t.Errorf("proxy results error : %d %d %d", succ, banned, failed)
+	}
+	if pool.Available() != 2 {
+		t.Errorf("one failure should not evict : %d", pool.Available())
+	}
+
+	pool.CheckAll(dl, "http://example.com/check")
+	if pool.Available() != 1 {
+		t.Errorf("check should evict the dead proxy : %d", pool.Available())
+	}
+	for i := 0; i < 3; i++ {
+		if u := pool.Get(); u.String() != good.URL {
+			t.Error("only the good proxy should be chosen")
+		}
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if pool.Available() != 3 {
+		t.Error("proxies should be admitted again after cooldown")
+	}
+}
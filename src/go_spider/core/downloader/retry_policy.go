@@ -0,0 +1,97 @@
+package downloader
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+import (
+	"go_spider/core/common/page"
+)
+
+// RetryPolicy decides whether a download is attempted again and how long to wait before it.
+// Pages that failed without a response (connection errors, timeouts) or while reading the body
+// are retried when RetryErrors is set,
+// pages with a status code in RetryStatus or a banned proxy are retried, and nothing else is.
+// The wait doubles after every attempt starting at BaseDelay up to MaxDelay, with up to 50% jitter.
+// A Retry-After header in seconds overrides the wait.
+type RetryPolicy struct {
+	MaxAttempts int
+	RetryErrors bool
+	RetryStatus map[int]bool
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// NewRetryPolicy returns the default policy: 3 attempts, retrying connection errors,
+// 408, 429 and 5xx gateway errors, waiting 500ms then 1s.
+func NewRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		RetryErrors: true,
+		RetryStatus: map[int]bool{
+			http.StatusRequestTimeout:      true,
+			http.StatusTooManyRequests:     true,
+			http.StatusInternalServerError: true,
+			http.StatusBadGateway:          true,
+			http.StatusServiceUnavailable:  true,
+			http.StatusGatewayTimeout:      true,
+		},
+		BaseDelay: 500 * time.Millisecond,
+		MaxDelay:  30 * time.Second,
+	}
+}
+
+// NoRetryPolicy returns a policy downloading every request once.
+func NoRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{MaxAttempts: 1}
+}
+
+// Backoff returns the wait before the next attempt and whether there is one,
+// given page p returned by attempt (counting from 1).
+func (this *RetryPolicy) Backoff(p *page.Page, attempt int) (time.Duration, bool) {
+	if attempt >= this.MaxAttempts || !this.Retryable(p) {
+		return 0, false
+	}
+
+	if header := p.GetHeader(); header != nil {
+		if secs, err := strconv.Atoi(header.Get("Retry-After")); err == nil && secs >= 0 {
+			delay := time.Duration(secs) * time.Second
+			if this.MaxDelay > 0 && delay > this.MaxDelay {
+				delay = this.MaxDelay
+			}
+			return delay, true
+		}
+	}
+
+	delay := this.BaseDelay
+	for i := 1; i < attempt && (this.MaxDelay <= 0 || delay < this.MaxDelay); i++ {
+		delay *= 2
+	}
+	if this.MaxDelay > 0 && delay > this.MaxDelay {
+		delay = this.MaxDelay
+	}
+	if delay > 0 {
+		delay += time.Duration(rand.Int63n(int64(delay)/2 + 1))
+	}
+	return delay, true
+}
+
+// Retryable reports whether the result of p is worth another attempt.
+func (this *RetryPolicy) Retryable(p *page.Page) bool {
+	if !p.IsSucc() && p.Errormsg() == ErrmsgProxyBanned {
+		// another proxy is chosen for the next attempt
+		return true
+	}
+	code := p.GetStatusCode()
+	if !p.IsSucc() && strings.HasPrefix(p.Errormsg(), ErrmsgReadBody) {
+		return this.RetryErrors
+	}
+	if code == 0 {
+		return !p.IsSucc() && this.RetryErrors && p.Errormsg() != "url is empty"
+	}
+	return this.RetryStatus[code]
+}
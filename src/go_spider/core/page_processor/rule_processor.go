@@ -0,0 +1,542 @@
+package page_processor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/xpath"
+	"gopkg.in/yaml.v2"
+)
+
+import (
+	"go_spider/core/common/mlog"
+	"go_spider/core/common/page"
+	"go_spider/core/common/request"
+)
+
+// RuleSet is the root of a json or yaml rule file driving RuleProcessor.
+// The keys of a yaml file are the same as the ones of a json file.
+//
+// Example:
+//
+//	{"rules": [{
+//		"url": "^http://github\\.com/[^/]+$",
+//		"fields": [
+//			{"name": "author", "css": ".vcard-fullname", "required": true},
+//			{"name": "repos", "css": ".repo-list-item", "list": true, "fields": [
+//				{"name": "name", "css": "h3 a"},
+//				{"name": "stars", "css": ".stars", "type": "int"}
+//			]}
+//		],
+//		"links": [{"css": ".pagination a", "resp_type": "html"}]
+//	}]}
+type RuleSet struct {
+	Rules []*PageRule `json:"rules"`
+}
+
+// PageRule applies to the pages whose url matches Url and whose urltag equals UrlTag.
+// Only the first matching PageRule is used for a page.
+type PageRule struct {
+	// The Url is a regexp matched against the page url, empty matches every url.
+	Url string `json:"url"`
+	// The UrlTag must equal the urltag of the page request when not empty.
+	UrlTag string       `json:"urltag"`
+	Fields []*FieldRule `json:"fields"`
+	Links  []*LinkRule  `json:"links"`
+
+	urlReg *regexp.Regexp
+}
+
+// FieldRule extracts one field by a CSS selector or an XPath on the html or a path on the json of the page.
+type FieldRule struct {
+	Name string `json:"name"`
+	// The Css is a selector relative to the parent selection.
+	Css string `json:"css"`
+	// The XPath is an expression relative to the parent selection, such as "./h3/a" or "//title".
+	// It may select attributes and text nodes, as "./a/@href" or "./text()", whose value is used as the text.
+	XPath string `json:"xpath"`
+	// The Attr is the attribute to read instead of the text, "html" reads the inner html.
+	Attr string `json:"attr"`
+	// The Json is a dot separated path relative to the parent value, such as "data.items.0.name".
+	Json string `json:"json"`
+	// The Regexp keeps the first submatch (or the whole match) of the extracted text.
+	Regexp string `json:"regexp"`
+	// The Type is "string" (default), "int", "float" or "bool".
+	Type string `json:"type"`
+	// The List extracts every match instead of the first one.
+	List bool `json:"list"`
+	// The Fields, when set, extract a record from each match instead of a value.
+	Fields []*FieldRule `json:"fields"`
+	// The Required skips the page when the field is empty.
+	Required bool `json:"required"`
+
+	reg   *regexp.Regexp
+	xexpr *xpath.Expr
+}
+
+// LinkRule extracts links that are added as target requests.
+type LinkRule struct {
+	// The Css or the XPath selects the link elements and Attr is the attribute holding the url, "href" by default.
+	// An XPath selecting attributes, as "//a/@href", gives the urls itself.
+	Css   string `json:"css"`
+	XPath string `json:"xpath"`
+	Attr  string `json:"attr"`
+	// The Json is a path to a url or a list of urls.
+	Json string `json:"json"`
+	// The Pattern is a regexp the absolute url must match, empty matches every url.
+	Pattern string `json:"pattern"`
+	// The RespType of the new requests, "html" by default.
+	RespType string `json:"resp_type"`
+	UrlTag   string `json:"urltag"`
+
+	reg   *regexp.Regexp
+	xexpr *xpath.Expr
+}
+
+// LoadRuleSet reads and compiles a rule file, a yaml file when its extension is .yaml or .yml
+// and a json file otherwise.
+func LoadRuleSet(path string) (*RuleSet, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return ParseYAMLRuleSet(b)
+	}
+	return ParseRuleSet(b)
+}
+
+// ParseYAMLRuleSet decodes and compiles yaml rules.
+func ParseYAMLRuleSet(b []byte) (*RuleSet, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	// the yaml is decoded by the json keys of the rules
+	js, err := json.Marshal(yamlToJson(v))
+	if err != nil {
+		return nil, err
+	}
+	return ParseRuleSet(js)
+}
+
+// The yamlToJson converts the maps decoded by yaml, whose keys are interface{}, to json objects.
+func yamlToJson(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for key, value := range t {
+			m[fmt.Sprint(key)] = yamlToJson(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range t {
+			t[i] = yamlToJson(value)
+		}
+	}
+	return v
+}
+
+// ParseRuleSet decodes and compiles json rules.
+func ParseRuleSet(b []byte) (*RuleSet, error) {
+	rs := &RuleSet{}
+	if err := json.Unmarshal(b, rs); err != nil {
+		return nil, err
+	}
+	if err := rs.Compile(); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+// Compile checks the rules and compiles their regexps.
+func (this *RuleSet) Compile() error {
+	var err error
+	for _, pr := range this.Rules {
+		if pr.Url != "" {
+			if pr.urlReg, err = regexp.Compile(pr.Url); err != nil {
+				return err
+			}
+		}
+		if err = compileFields(pr.Fields); err != nil {
+			return err
+		}
+		for _, lr := range pr.Links {
+			if lr.Css == "" && lr.XPath == "" && lr.Json == "" {
+				return errors.New("link rule needs css, xpath or json")
+			}
+			if lr.XPath != "" {
+				if lr.xexpr, err = xpath.Compile(lr.XPath); err != nil {
+					return errors.New("link rule has a bad xpath : " + err.Error())
+				}
+			}
+			if lr.Pattern != "" {
+				if lr.reg, err = regexp.Compile(lr.Pattern); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func compileFields(fields []*FieldRule) error {
+	var err error
+	for _, fr := range fields {
+		if fr.Name == "" {
+			return errors.New("field rule needs a name")
+		}
+		switch fr.Type {
+		case "", "string", "int", "float", "bool":
+		default:
+			return errors.New("field " + fr.Name + " has unknown type " + fr.Type)
+		}
+		if fr.Regexp != "" {
+			if fr.reg, err = regexp.Compile(fr.Regexp); err != nil {
+				return err
+			}
+		}
+		if fr.XPath != "" {
+			if fr.Css != "" {
+				return errors.New("field " + fr.Name + " has both css and xpath")
+			}
+			if fr.xexpr, err = xpath.Compile(fr.XPath); err != nil {
+				return errors.New("field " + fr.Name + " has a bad xpath : " + err.Error())
+			}
+		}
+		if err = compileFields(fr.Fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Match reports whether the rule applies to page p.
+func (this *PageRule) Match(p *page.Page) bool {
+	if this.UrlTag != "" && this.UrlTag != p.GetUrlTag() {
+		return false
+	}
+	return this.urlReg == nil || this.urlReg.MatchString(p.GetRequest().GetUrl())
+}
+
+// RuleProcessor is a generic PageProcessor driven by a RuleSet instead of Go code.
+// Html pages are extracted with the css and xpath rules and json pages with the json rules.
+// Fields are saved with typed values: lists as []interface{} and nested records as map[string]interface{}.
+type RuleProcessor struct {
+	rules *RuleSet
+}
+
+func NewRuleProcessor(rules *RuleSet) *RuleProcessor {
+	return &RuleProcessor{rules: rules}
+}
+
+func (this *RuleProcessor) Process(p *page.Page) {
+	if !p.IsSucc() {
+		mlog.LogInst().LogError(p.Errormsg())
+		return
+	}
+
+	for _, pr := range this.rules.Rules {
+		if pr.Match(p) {
+			this.processRule(p, pr)
+			return
+		}
+	}
+	p.SetSkip(true)
+}
+
+func (this *RuleProcessor) processRule(p *page.Page, pr *PageRule) {
+	var record map[string]interface{}
+	var ok bool
+	if doc := p.GetHtmlParser(); doc != nil {
+		record, ok = extractHtmlRecord(doc.Selection, pr.Fields)
+	} else if js := p.GetJson(); js != nil {
+		record, ok = extractJsonRecord(js.Interface(), pr.Fields)
+	} else {
+		record, ok = map[string]interface{}{}, len(pr.Fields) == 0
+	}
+
+	if !ok || len(record) == 0 {
+		p.SetSkip(true)
+	}
+	for name, value := range record {
+		p.AddFieldValue(name, value)
+	}
+
+	base, _ := url.Parse(p.GetRequest().GetUrl())
+	for _, lr := range pr.Links {
+		respType := lr.RespType
+		if respType == "" {
+			respType = "html"
+		}
+		for _, link := range extractLinks(p, lr) {
+			if base != nil {
+				u, err := base.Parse(link)
+				if err != nil {
+					continue
+				}
+				u.Fragment = ""
+				link = u.String()
+			}
+			if lr.reg != nil && !lr.reg.MatchString(link) {
+				continue
+			}
+			if lr.UrlTag == "" {
+				p.AddTargetRequest(link, respType)
+			} else {
+				p.AddTargetRequestWithParams(request.NewRequest(link, respType, lr.UrlTag, "GET", "", nil, nil, nil, nil))
+			}
+		}
+	}
+}
+
+// The extractHtmlRecord returns the record of fields and false when a required field is empty.
+func extractHtmlRecord(sel *goquery.Selection, fields []*FieldRule) (map[string]interface{}, bool) {
+	record := make(map[string]interface{}, len(fields))
+	for _, fr := range fields {
+		var values []interface{}
+		add := func(s *goquery.Selection) {
+			if len(fr.Fields) != 0 {
+				if sub, ok := extractHtmlRecord(s, fr.Fields); ok {
+					values = append(values, sub)
+				}
+				return
+			}
+
+			var text string
+			switch fr.Attr {
+			case "":
+				text = strings.TrimSpace(s.Text())
+			case "html":
+				text, _ = s.Html()
+			default:
+				text, _ = s.Attr(fr.Attr)
+			}
+			if v := convertText(fr, text); v != nil {
+				values = append(values, v)
+			}
+		}
+
+		if fr.xexpr != nil {
+			matches := xpathSelect(sel, fr.xexpr)
+			if !fr.List && len(matches) > 1 {
+				matches = matches[:1]
+			}
+			for _, m := range matches {
+				if m.sel != nil {
+					add(m.sel)
+				} else if len(fr.Fields) == 0 {
+					// an attribute or a text node
+					if v := convertText(fr, strings.TrimSpace(m.value)); v != nil {
+						values = append(values, v)
+					}
+				}
+			}
+		} else {
+			target := sel
+			if fr.Css != "" {
+				target = sel.Find(fr.Css)
+			}
+			if !fr.List {
+				target = target.First()
+			}
+			target.Each(func(i int, s *goquery.Selection) {
+				add(s)
+			})
+		}
+
+		if !setField(record, fr, values) {
+			return record, false
+		}
+	}
+	return record, true
+}
+
+// The extractJsonRecord is the json counterpart of extractHtmlRecord.
+func extractJsonRecord(data interface{}, fields []*FieldRule) (map[string]interface{}, bool) {
+	record := make(map[string]interface{}, len(fields))
+	for _, fr := range fields {
+		target := jsonPath(data, fr.Json)
+
+		var matches []interface{}
+		if arr, isArr := target.([]interface{}); isArr && (fr.List || len(fr.Fields) != 0) {
+			matches = arr
+		} else if target != nil {
+			matches = []interface{}{target}
+		}
+		if !fr.List && len(matches) > 1 {
+			matches = matches[:1]
+		}
+
+		var values []interface{}
+		for _, m := range matches {
+			if len(fr.Fields) != 0 {
+				if sub, ok := extractJsonRecord(m, fr.Fields); ok {
+					values = append(values, sub)
+				}
+				continue
+			}
+			if v := convertJson(fr, m); v != nil {
+				values = append(values, v)
+			}
+		}
+
+		if !setField(record, fr, values) {
+			return record, false
+		}
+	}
+	return record, true
+}
+
+// The setField saves values in record and returns false when a required field is empty.
+func setField(record map[string]interface{}, fr *FieldRule, values []interface{}) bool {
+	if fr.List {
+		if values == nil {
+			values = []interface{}{}
+		}
+		record[fr.Name] = values
+		return !fr.Required || len(values) != 0
+	}
+
+	if len(values) == 0 {
+		if fr.Required {
+			return false
+		}
+		if len(fr.Fields) == 0 && (fr.Type == "" || fr.Type == "string") {
+			record[fr.Name] = ""
+		}
+		return true
+	}
+	if s, ok := values[0].(string); ok && s == "" && fr.Required {
+		return false
+	}
+	record[fr.Name] = values[0]
+	return true
+}
+
+// The jsonPath walks the dot separated path through json objects and arrays.
+func jsonPath(data interface{}, path string) interface{} {
+	if path == "" {
+		return data
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch v := data.(type) {
+		case map[string]interface{}:
+			data = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			data = v[i]
+		default:
+			return nil
+		}
+	}
+	return data
+}
+
+func convertJson(fr *FieldRule, v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case string:
+		return convertText(fr, t)
+	case map[string]interface{}, []interface{}:
+		return v
+	default:
+		return convertText(fr, fmt.Sprint(t))
+	}
+}
+
+// The convertText applies the regexp and the type of fr to text, nil means no value.
+func convertText(fr *FieldRule, text string) interface{} {
+	if fr.reg != nil {
+		m := fr.reg.FindStringSubmatch(text)
+		if m == nil {
+			return nil
+		}
+		text = m[len(m)-1]
+	}
+
+	switch fr.Type {
+	case "int":
+		n, err := strconv.ParseInt(strings.Replace(strings.TrimSpace(text), ",", "", -1), 10, 64)
+		if err != nil {
+			return nil
+		}
+		return n
+	case "float":
+		f, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(text), ",", "", -1), 64)
+		if err != nil {
+			return nil
+		}
+		return f
+	case "bool":
+		b, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return nil
+		}
+		return b
+	}
+	return text
+}
+
+func extractLinks(p *page.Page, lr *LinkRule) []string {
+	var links []string
+	attr := lr.Attr
+	if attr == "" {
+		attr = "href"
+	}
+	add := func(link string) {
+		if link = strings.TrimSpace(link); link != "" {
+			links = append(links, link)
+		}
+	}
+	if lr.Css != "" {
+		if doc := p.GetHtmlParser(); doc != nil {
+			doc.Find(lr.Css).Each(func(i int, s *goquery.Selection) {
+				link, _ := s.Attr(attr)
+				add(link)
+			})
+		}
+	}
+	if lr.xexpr != nil {
+		if doc := p.GetHtmlParser(); doc != nil {
+			for _, m := range xpathSelect(doc.Selection, lr.xexpr) {
+				if m.sel != nil {
+					link, _ := m.sel.Attr(attr)
+					add(link)
+				} else {
+					add(m.value)
+				}
+			}
+		}
+	}
+	if lr.Json != "" {
+		if js := p.GetJson(); js != nil {
+			switch v := jsonPath(js.Interface(), lr.Json).(type) {
+			case string:
+				links = append(links, v)
+			case []interface{}:
+				for _, e := range v {
+					if link, ok := e.(string); ok {
+						links = append(links, link)
+					}
+				}
+			}
+		}
+	}
+	return links
+}
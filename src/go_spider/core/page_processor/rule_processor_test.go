@@ -0,0 +1,168 @@
+package page_processor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/bitly/go-simplejson"
+)
+
+import (
+	"go_spider/core/common/page"
+	"go_spider/core/common/request"
+)
+
+const testRules = `{"rules": [
+	{
+		"url": "/repos$",
+		"fields": [
+			{"name": "author", "css": ".author", "required": true},
+			{"name": "count", "css": ".count", "type": "int", "regexp": "(\\d+) repos"},
+			{"name": "repos", "css": "li.repo", "list": true, "fields": [
+				{"name": "name", "css": "a"},
+				{"name": "stars", "css": ".stars", "type": "int"}
+			]},
+			{"name": "tags", "css": ".tag", "list": true}
+		],
+		"links": [{"css": "li.repo a", "pattern": "/repo/", "urltag": "repo"}]
+	},
+	{
+		"urltag": "api",
+		"fields": [
+			{"name": "total", "json": "data.total", "type": "int"},
+			{"name": "names", "json": "data.items", "list": true, "fields": [{"name": "name", "json": "name"}]},
+			{"name": "first", "json": "data.items.0.name"}
+		],
+		"links": [{"json": "data.next", "resp_type": "json", "urltag": "api"}]
+	}
+]}`
+
+const testHtml = `<html><body>
+<div class="author"> hu17889 </div>
+<div class="count">2 repos</div>
+<ul>
+	<li class="repo"><a href="/repo/go_spider">go_spider</a><span class="stars">1,024</span></li>
+	<li class="repo"><a href="http://other.com/x">other</a><span class="stars">3</span></li>
+</ul>
+<span class="tag">go</span><span class="tag">spider</span>
+</body></html>`
+
+func TestRuleProcessorHtml(t *testing.T) {
+	rs, err := ParseRuleSet([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := page.NewPage(request.NewRequest("http://github.com/hu17889/repos", "html", "", "GET", "", nil, nil, nil, nil))
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(testHtml))
+	p.SetHtmlParser(doc).SetBodyStr(testHtml)
+
+	NewRuleProcessor(rs).Process(p)
+	items := p.GetPageItems().GetAll()
+	fmt.Println(items)
+	if items["author"] != "hu17889" || items["count"] != "2" {
+		t.Error("field extract error")
+	}
+	if items["repos"] != `[{"name":"go_spider","stars":1024},{"name":"other","stars":3}]` {
+		t.Error("nested list extract error : " + items["repos"])
+	}
+	if items["tags"] != `["go","spider"]` {
+		t.Error("list extract error : " + items["tags"])
+	}
+
+	reqs := p.GetTargetRequests()
+	if len(reqs) != 1 || reqs[0].GetUrl() != "http://github.com/repo/go_spider" || reqs[0].GetUrlTag() != "repo" {
+		t.Errorf("link extract error : %v", reqs)
+	}
+
+	p = page.NewPage(request.NewRequest("http://github.com/nobody/repos", "html", "", "GET", "", nil, nil, nil, nil))
+	doc, _ = goquery.NewDocumentFromReader(strings.NewReader("<html><body></body></html>"))
+	p.SetHtmlParser(doc)
+	NewRuleProcessor(rs).Process(p)
+	if !p.GetSkip() {
+		t.Error("page without required field should be skipped")
+	}
+}
+
+func TestRuleProcessorJson(t *testing.T) {
+	rs, err := ParseRuleSet([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := `{"data": {"total": 2, "items": [{"name": "a"}, {"name": "b"}], "next": "/api?page=2"}}`
+	p := page.NewPage(request.NewRequest("http://github.com/api", "json", "api", "GET", "", nil, nil, nil, nil))
+	js, _ := simplejson.NewJson([]byte(body))
+	p.SetJson(js).SetBodyStr(body)
+
+	NewRuleProcessor(rs).Process(p)
+	items := p.GetPageItems().GetAll()
+	fmt.Println(items)
+	if items["total"] != "2" || items["first"] != "a" || items["names"] != `[{"name":"a"},{"name":"b"}]` {
+		t.Error("json extract error")
+	}
+
+	reqs := p.GetTargetRequests()
+	if len(reqs) != 1 || reqs[0].GetUrl() != "http://github.com/api?page=2" || reqs[0].GetResponseType() != "json" {
+		t.Errorf("json link extract error : %v", reqs)
+	}
+}
+
+const testYamlRules = `
+rules:
+  - url: /repos$
+    fields:
+      - name: author
+        xpath: //div[@class="author"]
+        required: true
+      - name: repos
+        xpath: //li[@class="repo"]
+        list: true
+        fields:
+          - {name: name, xpath: ./a}
+          - {name: href, xpath: ./a/@href}
+          - {name: stars, xpath: "./span[@class='stars']/text()", type: int}
+      - name: tags
+        xpath: //span[@class="tag"]
+        list: true
+    links:
+      - xpath: //li[@class="repo"]/a
+        pattern: /repo/
+        urltag: repo
+      - xpath: //li[@class="repo"]/a/@href
+        pattern: other\.com
+`
+
+func TestRuleProcessorXPathYaml(t *testing.T) {
+	rs, err := ParseYAMLRuleSet([]byte(testYamlRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := page.NewPage(request.NewRequest("http://github.com/hu17889/repos", "html", "", "GET", "", nil, nil, nil, nil))
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(testHtml))
+	p.SetHtmlParser(doc).SetBodyStr(testHtml)
+
+	NewRuleProcessor(rs).Process(p)
+	items := p.GetPageItems().GetAll()
+	if items["author"] != "hu17889" || items["tags"] != `["go","spider"]` {
+		t.Errorf("xpath field extract error : %v", items)
+	}
+	if items["repos"] != `[{"href":"/repo/go_spider","name":"go_spider","stars":1024},{"href":"http://other.com/x","name":"other","stars":3}]` {
+		t.Error("xpath nested list extract error : " + items["repos"])
+	}
+
+	reqs := p.GetTargetRequests()
+	if len(reqs) != 2 || reqs[0].GetUrl() != "http://github.com/repo/go_spider" || reqs[0].GetUrlTag() != "repo" ||
+		reqs[1].GetUrl() != "http://other.com/x" {
+		t.Errorf("xpath link extract error : %v", reqs)
+	}
+
+	if _, err := ParseRuleSet([]byte(`{"rules": [{"fields": [{"name": "a", "xpath": "//["}]}]}`)); err == nil {
+		t.Error("a bad xpath should fail to compile")
+	}
+}
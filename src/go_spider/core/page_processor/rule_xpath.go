@@ -0,0 +1,178 @@
+package page_processor
+
+import (
+	"strings"
+)
+
+import (
+	"github.com/PuerkitoBio/goquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+)
+
+// The xpathMatch is an element found by an xpath, or the value of an attribute or a text node.
+type xpathMatch struct {
+	sel   *goquery.Selection
+	value string
+}
+
+// The xpathSelect evaluates expr relative to every node of sel. Absolute paths start at the document.
+func xpathSelect(sel *goquery.Selection, expr *xpath.Expr) []xpathMatch {
+	var matches []xpathMatch
+	for _, node := range sel.Nodes {
+		root := node
+		for root.Parent != nil {
+			root = root.Parent
+		}
+		it := expr.Select(&htmlNavigator{root: root, curr: node, attr: -1})
+		for it.MoveNext() {
+			nav := it.Current().(*htmlNavigator)
+			if nav.curr.Type == html.ElementNode && nav.attr == -1 {
+				// a selection of the single node, which may be outside of sel for an absolute path
+				matches = append(matches, xpathMatch{sel: sel.FindNodes().AddNodes(nav.curr)})
+			} else {
+				matches = append(matches, xpathMatch{value: nav.Value()})
+			}
+		}
+	}
+	return matches
+}
+
+// The htmlNavigator is the xpath.NodeNavigator of a html tree, attr is the index of the current
+// attribute of an element or -1.
+type htmlNavigator struct {
+	root, curr *html.Node
+	attr       int
+}
+
+func (this *htmlNavigator) NodeType() xpath.NodeType {
+	switch this.curr.Type {
+	case html.CommentNode:
+		return xpath.CommentNode
+	case html.TextNode:
+		return xpath.TextNode
+	case html.DocumentNode:
+		return xpath.RootNode
+	case html.ElementNode:
+		if this.attr != -1 {
+			return xpath.AttributeNode
+		}
+		return xpath.ElementNode
+	}
+	// the doctype and error nodes are skipped as comments
+	return xpath.CommentNode
+}
+
+func (this *htmlNavigator) LocalName() string {
+	if this.attr != -1 {
+		return this.curr.Attr[this.attr].Key
+	}
+	return this.curr.Data
+}
+
+func (this *htmlNavigator) Prefix() string {
+	return ""
+}
+
+func (this *htmlNavigator) Value() string {
+	switch this.curr.Type {
+	case html.CommentNode, html.TextNode:
+		return this.curr.Data
+	case html.ElementNode:
+		if this.attr != -1 {
+			return this.curr.Attr[this.attr].Val
+		}
+		return nodeText(this.curr)
+	}
+	return ""
+}
+
+func (this *htmlNavigator) Copy() xpath.NodeNavigator {
+	n := *this
+	return &n
+}
+
+func (this *htmlNavigator) MoveToRoot() {
+	this.curr = this.root
+	this.attr = -1
+}
+
+func (this *htmlNavigator) MoveToParent() bool {
+	if this.attr != -1 {
+		this.attr = -1
+		return true
+	}
+	if this.curr.Parent != nil {
+		this.curr = this.curr.Parent
+		return true
+	}
+	return false
+}
+
+func (this *htmlNavigator) MoveToNextAttribute() bool {
+	if this.attr >= len(this.curr.Attr)-1 {
+		return false
+	}
+	this.attr++
+	return true
+}
+
+func (this *htmlNavigator) MoveToChild() bool {
+	if this.attr != -1 || this.curr.FirstChild == nil {
+		return false
+	}
+	this.curr = this.curr.FirstChild
+	return true
+}
+
+func (this *htmlNavigator) MoveToFirst() bool {
+	if this.attr != -1 || this.curr.PrevSibling == nil {
+		return false
+	}
+	for this.curr.PrevSibling != nil {
+		this.curr = this.curr.PrevSibling
+	}
+	return true
+}
+
+func (this *htmlNavigator) MoveToNext() bool {
+	if this.attr != -1 || this.curr.NextSibling == nil {
+		return false
+	}
+	this.curr = this.curr.NextSibling
+	return true
+}
+
+func (this *htmlNavigator) MoveToPrevious() bool {
+	if this.attr != -1 || this.curr.PrevSibling == nil {
+		return false
+	}
+	this.curr = this.curr.PrevSibling
+	return true
+}
+
+func (this *htmlNavigator) MoveTo(other xpath.NodeNavigator) bool {
+	nav, ok := other.(*htmlNavigator)
+	if !ok || nav.root != this.root {
+		return false
+	}
+	this.curr = nav.curr
+	this.attr = nav.attr
+	return true
+}
+
+// The nodeText is the text of node and its descendants.
+func nodeText(node *html.Node) string {
+	var b strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(node)
+	return b.String()
+}
@@ -1,23 +1,44 @@
 package pipeline
 
+import (
+	"sync"
+)
+
 import (
 	"go_spider/core/common/com_interfaces"
 	"go_spider/core/common/page_items"
 )
 
 type CollectPipelinePageItems struct {
+	locker    *sync.Mutex
 	collector []*page_items.PageItems
 }
 
 func NewCollectPipelinePageItems() *CollectPipelinePageItems {
 	collector := make([]*page_items.PageItems, 0)
-	return &CollectPipelinePageItems{collector}
+	return &CollectPipelinePageItems{locker: new(sync.Mutex), collector: collector}
 }
 
 func (this *CollectPipelinePageItems) Process(items *page_items.PageItems, t com_interfaces.Task) {
+	this.locker.Lock()
 	this.collector = append(this.collector, items)
+	this.locker.Unlock()
 }
 
 func (this *CollectPipelinePageItems) GetCollected() []*page_items.PageItems {
+	this.locker.Lock()
+	defer this.locker.Unlock()
 	return this.collector
 }
+
+// GetCollectedRecords returns the records of all collected PageItems.
+func (this *CollectPipelinePageItems) GetCollectedRecords() []page_items.Record {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var records []page_items.Record
+	for _, items := range this.collector {
+		records = append(records, items.GetRecords()...)
+	}
+	return records
+}
@@ -14,3 +14,17 @@ type CollectionPipline interface {
 	// The GetCollected returns result saved in in process's memory temporarily.
 	GetCollected() []*page_items.PageItems
 }
+
+// The FlushPipeline is a Pipeline that buffers items.
+// Flush is called by Sipder when the crawl ends.
+type FlushPipeline interface {
+	Pipeline
+	Flush() error
+}
+
+// The ClosePipeline is a Pipeline that holds resources such as files or connections.
+// Close is called by Sipder when the crawl ends, after Flush.
+type ClosePipeline interface {
+	Pipeline
+	Close() error
+}
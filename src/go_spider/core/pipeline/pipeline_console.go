@@ -2,6 +2,7 @@ package pipeline
 
 import (
 	"fmt"
+	"sort"
 )
 
 import (
@@ -17,10 +18,22 @@ func NewPipelineConsole() *PipelineConsole {
 }
 
 func (this *PipelineConsole) Process(items *page_items.PageItems, t com_interfaces.Task) {
-	fmt.Printf("----------------------------------------------------------------------------------------------")
-	fmt.Printf("Crawled url :\t" + items.GetRequest().GetUrl() + "\n")
-	fmt.Printf("Crawled result : ")
-	for key, value := range items.GetAll() {
-		fmt.Printf(key + "\t:\t" + value)
+	fmt.Println("----------------------------------------------------------------------------------------------")
+	fmt.Println("Crawled url :\t" + items.GetRequest().GetUrl())
+	fmt.Println("Crawled result : ")
+	for _, record := range items.GetRecords() {
+		for _, key := range sortedKeys(record) {
+			fmt.Println(key + "\t:\t" + page_items.ValueString(record[key]))
+		}
 	}
 }
+
+// The sortedKeys returns the keys of record in a stable order for output.
+func sortedKeys(record page_items.Record) []string {
+	keys := make([]string, 0, len(record))
+	for key := range record {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"encoding/csv"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+import (
+	"go_spider/core/common/com_interfaces"
+	"go_spider/core/common/mlog"
+	"go_spider/core/common/page_items"
+)
+
+// The PipelineCsv writes every record as one csv row with a stable column order.
+// The columns are given to the constructor, or taken sorted from the first record when nil;
+// fields of later records that are not columns are dropped.
+// Values that are not strings are json encoded.
+type PipelineCsv struct {
+	locker      *sync.Mutex
+	w           *csv.Writer
+	closer      io.Closer
+	columns     []string
+	writeHeader bool
+	urlField    string
+	flushEvery  int
+	pending     int
+}
+
+// NewPipelineCsv appends to the csv file at path. The header row is written when the file is empty.
+func NewPipelineCsv(path string, columns []string) *PipelineCsv {
+	pFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic("File '" + path + "' in PipelineCsv open failed.")
+	}
+
+	this := NewPipelineCsvWriter(pFile, columns)
+	this.closer = pFile
+	if fi, err := pFile.Stat(); err == nil && fi.Size() > 0 {
+		this.writeHeader = false
+	}
+	return this
+}
+
+// NewPipelineCsvWriter writes to w, which is not closed by Close.
+func NewPipelineCsvWriter(w io.Writer, columns []string) *PipelineCsv {
+	return &PipelineCsv{locker: new(sync.Mutex), w: csv.NewWriter(w), columns: columns, writeHeader: true, flushEvery: 100}
+}
+
+// SetUrlField adds the crawled url to every record under field name.
+// The field is added as a column when the columns are taken from the first record.
+func (this *PipelineCsv) SetUrlField(name string) *PipelineCsv {
+	this.urlField = name
+	return this
+}
+
+// SetFlushEvery sets how many rows are buffered before they are written out.
+func (this *PipelineCsv) SetFlushEvery(n int) *PipelineCsv {
+	this.flushEvery = n
+	return this
+}
+
+func (this *PipelineCsv) Process(items *page_items.PageItems, t com_interfaces.Task) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	for _, record := range items.GetRecords() {
+		record = withUrlField(record, this.urlField, items.GetRequest().GetUrl())
+		if this.columns == nil {
+			this.columns = make([]string, 0, len(record))
+			for key := range record {
+				this.columns = append(this.columns, key)
+			}
+			sort.Strings(this.columns)
+		}
+		if this.writeHeader {
+			this.w.Write(this.columns)
+			this.writeHeader = false
+		}
+
+		row := make([]string, len(this.columns))
+		for i, column := range this.columns {
+			row[i] = page_items.ValueString(record[column])
+		}
+		this.w.Write(row)
+		this.pending++
+	}
+
+	if this.pending >= this.flushEvery {
+		if err := this.flush(); err != nil {
+			mlog.LogInst().LogError("PipelineCsv write error : " + err.Error())
+		}
+	}
+}
+
+func (this *PipelineCsv) Flush() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.flush()
+}
+
+func (this *PipelineCsv) Close() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	err := this.flush()
+	if this.closer != nil {
+		if cerr := this.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (this *PipelineCsv) flush() error {
+	this.pending = 0
+	this.w.Flush()
+	return this.w.Error()
+}
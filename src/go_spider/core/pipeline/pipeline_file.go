@@ -27,7 +27,13 @@ func (this *PipelineFile) Process(items *page_items.PageItems, t com_interfaces.
 	this.pFile.WriteString("----------------------------------------------------------------------------------------------\n")
 	this.pFile.WriteString("Crawled url :\t" + items.GetRequest().GetUrl() + "\n")
 	this.pFile.WriteString("Crawled result : \n")
-	for key, value := range items.GetAll() {
-		this.pFile.WriteString(key + "\t:\t" + value + "\n")
+	for _, record := range items.GetRecords() {
+		for _, key := range sortedKeys(record) {
+			this.pFile.WriteString(key + "\t:\t" + page_items.ValueString(record[key]) + "\n")
+		}
 	}
 }
+
+func (this *PipelineFile) Close() error {
+	return this.pFile.Close()
+}
@@ -0,0 +1,114 @@
+package pipeline
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+import (
+	"go_spider/core/common/com_interfaces"
+	"go_spider/core/common/mlog"
+	"go_spider/core/common/page_items"
+)
+
+// The PipelineJsonLines writes every record as one json object per line.
+// Lines are buffered and written out every flushEvery records and when the crawl ends.
+type PipelineJsonLines struct {
+	locker     *sync.Mutex
+	w          *bufio.Writer
+	closer     io.Closer
+	urlField   string
+	flushEvery int
+	pending    int
+}
+
+func NewPipelineJsonLines(path string) *PipelineJsonLines {
+	pFile, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		panic("File '" + path + "' in PipelineJsonLines open failed.")
+	}
+
+	this := NewPipelineJsonLinesWriter(pFile)
+	this.closer = pFile
+	return this
+}
+
+// NewPipelineJsonLinesWriter writes to w, which is not closed by Close.
+func NewPipelineJsonLinesWriter(w io.Writer) *PipelineJsonLines {
+	return &PipelineJsonLines{locker: new(sync.Mutex), w: bufio.NewWriter(w), flushEvery: 100}
+}
+
+// SetUrlField adds the crawled url to every record under field name.
+func (this *PipelineJsonLines) SetUrlField(name string) *PipelineJsonLines {
+	this.urlField = name
+	return this
+}
+
+// SetFlushEvery sets how many records are buffered before they are written out.
+func (this *PipelineJsonLines) SetFlushEvery(n int) *PipelineJsonLines {
+	this.flushEvery = n
+	return this
+}
+
+func (this *PipelineJsonLines) Process(items *page_items.PageItems, t com_interfaces.Task) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	for _, record := range items.GetRecords() {
+		record = withUrlField(record, this.urlField, items.GetRequest().GetUrl())
+		b, err := json.Marshal(record)
+		if err != nil {
+			mlog.LogInst().LogError("PipelineJsonLines encode error : " + err.Error())
+			continue
+		}
+		this.w.Write(b)
+		this.w.WriteByte('\n')
+		this.pending++
+	}
+
+	if this.pending >= this.flushEvery {
+		if err := this.flush(); err != nil {
+			mlog.LogInst().LogError("PipelineJsonLines write error : " + err.Error())
+		}
+	}
+}
+
+func (this *PipelineJsonLines) Flush() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.flush()
+}
+
+func (this *PipelineJsonLines) Close() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	err := this.flush()
+	if this.closer != nil {
+		if cerr := this.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (this *PipelineJsonLines) flush() error {
+	this.pending = 0
+	return this.w.Flush()
+}
+
+// The withUrlField returns a copy of record holding url under field name, or record itself when name is empty.
+func withUrlField(record page_items.Record, name string, url string) page_items.Record {
+	if name == "" {
+		return record
+	}
+	r := make(page_items.Record, len(record)+1)
+	for key, value := range record {
+		r[key] = value
+	}
+	r[name] = url
+	return r
+}
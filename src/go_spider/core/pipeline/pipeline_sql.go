@@ -0,0 +1,170 @@
+package pipeline
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+import (
+	"go_spider/core/common/com_interfaces"
+	"go_spider/core/common/mlog"
+	"go_spider/core/common/page_items"
+)
+
+// The PipelineSql writes records to a table of a database/sql database.
+// Records are buffered and written in one transaction every batchSize records and when the crawl ends.
+// When a key column is set, rows are upserted by that column, which must be a primary or unique key.
+//
+// The dialect selects the upsert syntax and placeholders:
+// "mysql" uses ON DUPLICATE KEY UPDATE, "sqlite" and "postgres" use ON CONFLICT.
+type PipelineSql struct {
+	locker    *sync.Mutex
+	db        *sql.DB
+	dialect   string
+	table     string
+	columns   []string
+	key       string
+	urlField  string
+	batchSize int
+	buffer    []page_items.Record
+}
+
+// NewPipelineSql writes the columns of records to table. The db is not closed by Close.
+func NewPipelineSql(db *sql.DB, dialect string, table string, columns []string) *PipelineSql {
+	return &PipelineSql{
+		locker:    new(sync.Mutex),
+		db:        db,
+		dialect:   dialect,
+		table:     table,
+		columns:   columns,
+		batchSize: 100,
+	}
+}
+
+// SetKey sets the column rows are upserted by.
+func (this *PipelineSql) SetKey(column string) *PipelineSql {
+	this.key = column
+	return this
+}
+
+// SetUrlField adds the crawled url to every record under field name, which should be one of the columns.
+func (this *PipelineSql) SetUrlField(name string) *PipelineSql {
+	this.urlField = name
+	return this
+}
+
+// SetBatchSize sets how many records are buffered before they are written.
+func (this *PipelineSql) SetBatchSize(n int) *PipelineSql {
+	this.batchSize = n
+	return this
+}
+
+func (this *PipelineSql) Process(items *page_items.PageItems, t com_interfaces.Task) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	for _, record := range items.GetRecords() {
+		this.buffer = append(this.buffer, withUrlField(record, this.urlField, items.GetRequest().GetUrl()))
+	}
+
+	if len(this.buffer) >= this.batchSize {
+		if err := this.flush(); err != nil {
+			mlog.LogInst().LogError("PipelineSql write error : " + err.Error())
+		}
+	}
+}
+
+func (this *PipelineSql) Flush() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.flush()
+}
+
+func (this *PipelineSql) Close() error {
+	return this.Flush()
+}
+
+// The flush writes the buffer in one transaction. The buffer is dropped even when writing fails,
+// so that one bad record does not block the pipeline.
+func (this *PipelineSql) flush() error {
+	if len(this.buffer) == 0 {
+		return nil
+	}
+	buffer := this.buffer
+	this.buffer = nil
+
+	tx, err := this.db.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(this.insertSql())
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer stmt.Close()
+
+	for _, record := range buffer {
+		args := make([]interface{}, len(this.columns))
+		for i, column := range this.columns {
+			args[i] = sqlValue(record[column])
+		}
+		if _, err = stmt.Exec(args...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (this *PipelineSql) insertSql() string {
+	placeholders := make([]string, len(this.columns))
+	for i := range this.columns {
+		if this.dialect == "postgres" {
+			placeholders[i] = "$" + strconv.Itoa(i+1)
+		} else {
+			placeholders[i] = "?"
+		}
+	}
+
+	s := "INSERT INTO " + this.table + " (" + strings.Join(this.columns, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")"
+	if this.key == "" {
+		return s
+	}
+
+	var updates []string
+	for _, column := range this.columns {
+		if column == this.key {
+			continue
+		}
+		if this.dialect == "mysql" {
+			updates = append(updates, column+" = VALUES("+column+")")
+		} else {
+			updates = append(updates, column+" = excluded."+column)
+		}
+	}
+
+	if this.dialect == "mysql" {
+		if len(updates) == 0 {
+			return s + " ON DUPLICATE KEY UPDATE " + this.key + " = " + this.key
+		}
+		return s + " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+	}
+	if len(updates) == 0 {
+		return s + " ON CONFLICT (" + this.key + ") DO NOTHING"
+	}
+	return s + " ON CONFLICT (" + this.key + ") DO UPDATE SET " + strings.Join(updates, ", ")
+}
+
+// The sqlValue keeps values a sql driver accepts and json encodes lists and records.
+func sqlValue(value interface{}) interface{} {
+	switch value.(type) {
+	case nil, string, bool, int, int64, float64:
+		return value
+	case int8, int16, int32, uint, uint8, uint16, uint32, uint64, float32:
+		return value
+	}
+	return page_items.ValueString(value)
+}
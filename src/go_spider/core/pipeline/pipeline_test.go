@@ -0,0 +1,146 @@
+package pipeline
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+import (
+	"go_spider/core/common/page_items"
+	"go_spider/core/common/request"
+)
+
+type testTask struct{}
+
+func (testTask) Taskname() string {
+	return "test"
+}
+
+func newTestItems(url string, records ...page_items.Record) *page_items.PageItems {
+	items := page_items.NewPageItems(request.NewRequest(url, "html", "", "GET", "", nil, nil, nil, nil))
+	for _, r := range records {
+		items.AddRecord(r)
+	}
+	return items
+}
+
+func TestPipelineJsonLines(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPipelineJsonLinesWriter(&buf).SetUrlField("url").SetFlushEvery(10)
+	p.Process(newTestItems("http://a.com", page_items.Record{"name": "a", "tags": []interface{}{"x"}}, page_items.Record{"name": "b"}), testTask{})
+	if buf.Len() != 0 {
+		t.Error("records should be buffered")
+	}
+	if err := p.Close(); err != nil {
+		t.Error(err)
+	}
+
+	fmt.Print(buf.String())
+	expected := `{"name":"a","tags":["x"],"url":"http://a.com"}` + "\n" + `{"name":"b","url":"http://a.com"}` + "\n"
+	if buf.String() != expected {
+		t.Error("json lines error")
+	}
+}
+
+func TestPipelineCsv(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewPipelineCsvWriter(&buf, nil).SetFlushEvery(1)
+	p.Process(newTestItems("http://a.com", page_items.Record{"name": "a", "stars": 3}), testTask{})
+	p.Process(newTestItems("http://b.com", page_items.Record{"stars": 4, "name": "b,c", "other": 1}), testTask{})
+	p.Close()
+
+	fmt.Print(buf.String())
+	if buf.String() != "name,stars\na,3\n\"b,c\",4\n" {
+		t.Error("csv error")
+	}
+}
+
+// The testDriver is a database/sql driver standing in for a real database, it records executed statements.
+type testDriver struct {
+	locker *sync.Mutex
+	execs  []string
+	fail   bool
+}
+
+type testConn struct{ d *testDriver }
+type testStmt struct {
+	d     *testDriver
+	query string
+}
+
+func (this *testDriver) Open(name string) (driver.Conn, error) { return &testConn{this}, nil }
+func (this *testConn) Prepare(query string) (driver.Stmt, error) {
+	return &testStmt{this.d, query}, nil
+}
+func (this *testConn) Close() error              { return nil }
+func (this *testConn) Begin() (driver.Tx, error) { return this, nil }
+func (this *testConn) Commit() error             { return nil }
+func (this *testConn) Rollback() error           { return nil }
+func (this *testStmt) Close() error              { return nil }
+func (this *testStmt) NumInput() int             { return strings.Count(this.query, "?") }
+func (this *testStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return nil, errors.New("not supported")
+}
+func (this *testStmt) Exec(args []driver.Value) (driver.Result, error) {
+	this.d.locker.Lock()
+	defer this.d.locker.Unlock()
+	if this.d.fail {
+		return nil, errors.New("exec failed")
+	}
+	this.d.execs = append(this.d.execs, fmt.Sprint(this.query, args))
+	return driver.RowsAffected(1), nil
+}
+
+var testSqlDriver = &testDriver{locker: new(sync.Mutex)}
+
+func init() {
+	sql.Register("pipeline_test", testSqlDriver)
+}
+
+func TestPipelineSql(t *testing.T) {
+	db, err := sql.Open("pipeline_test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	p := NewPipelineSql(db, "mysql", "repos", []string{"url", "name", "tags"}).SetKey("url").SetUrlField("url").SetBatchSize(2)
+	p.Process(newTestItems("http://a.com", page_items.Record{"name": "a", "tags": []interface{}{"x"}}), testTask{})
+	if len(testSqlDriver.execs) != 0 {
+		t.Error("records should be buffered")
+	}
+	p.Process(newTestItems("http://b.com", page_items.Record{"name": "b"}), testTask{})
+	if len(testSqlDriver.execs) != 2 {
+		t.Errorf("batch should be written : %v", testSqlDriver.execs)
+	}
+	p.Process(newTestItems("http://c.com", page_items.Record{"name": "c"}), testTask{})
+	if err := p.Close(); err != nil {
+		t.Error(err)
+	}
+
+	for _, s := range testSqlDriver.execs {
+		fmt.Println(s)
+	}
+	expected := "INSERT INTO repos (url, name, tags) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE name = VALUES(name), tags = VALUES(tags)[http://a.com a [\"x\"]]"
+	if len(testSqlDriver.execs) != 3 || testSqlDriver.execs[0] != expected {
+		t.Error("sql pipeline error")
+	}
+
+	sqlite := NewPipelineSql(db, "sqlite", "repos", []string{"url", "name"}).SetKey("url")
+	if s := sqlite.insertSql(); s != "INSERT INTO repos (url, name) VALUES (?, ?) ON CONFLICT (url) DO UPDATE SET name = excluded.name" {
+		t.Error("sqlite upsert error : " + s)
+	}
+
+	testSqlDriver.fail = true
+	p.Process(newTestItems("http://d.com", page_items.Record{"name": "d"}), testTask{})
+	if err := p.Flush(); err == nil {
+		t.Error("exec error should be returned")
+	}
+	testSqlDriver.fail = false
+}
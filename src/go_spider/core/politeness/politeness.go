@@ -0,0 +1,257 @@
+// Package politeness keeps a crawl polite to every host it visits.
+// It limits concurrent requests per host, spaces requests to one host by a delay
+// and honours robots.txt Allow/Disallow and Crawl-delay rules.
+package politeness
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+import (
+	"golang.org/x/net/context"
+)
+
+import (
+	"go_spider/core/common/mlog"
+	"go_spider/core/common/request"
+	"go_spider/core/downloader"
+)
+
+// The SkipFunc is called with the request and the reason when a request is skipped by policy.
+type SkipFunc func(req *request.Request, reason string)
+
+// Politeness is the host-aware layer used by Sipder around each download.
+// A request should be checked by Allowed first, then downloaded between Acquire and Release.
+type Politeness struct {
+	locker *sync.Mutex
+	cond   *sync.Cond
+
+	pDownloader downloader.Downloader
+	userAgent   string
+	obeyRobots  bool
+	maxPerHost  int
+	delay       time.Duration
+
+	hosts  map[string]*hostState
+	robots map[string]*robotsEntry
+
+	skipped int
+	onSkip  SkipFunc
+}
+
+type hostState struct {
+	active int
+	next   time.Time
+}
+
+type robotsEntry struct {
+	once  sync.Once
+	rules *RobotsRules
+}
+
+// NewPoliteness returns a Politeness fetching robots.txt with the downloader d.
+// By default robots.txt is obeyed, 1 request per host runs at once and there is no extra delay.
+func NewPoliteness(d downloader.Downloader) *Politeness {
+	locker := new(sync.Mutex)
+	return &Politeness{
+		locker:      locker,
+		cond:        sync.NewCond(locker),
+		pDownloader: d,
+		userAgent:   "go_spider",
+		obeyRobots:  true,
+		maxPerHost:  1,
+		hosts:       make(map[string]*hostState),
+		robots:      make(map[string]*robotsEntry),
+	}
+}
+
+// SetUserAgent sets the agent matched against robots.txt User-agent lines and sent when fetching it.
+func (this *Politeness) SetUserAgent(agent string) *Politeness {
+	this.userAgent = agent
+	return this
+}
+
+// SetObeyRobots turns robots.txt checking on or off.
+func (this *Politeness) SetObeyRobots(obey bool) *Politeness {
+	this.obeyRobots = obey
+	return this
+}
+
+// SetMaxPerHost sets how many requests to one host may run at once. 0 means no limit.
+func (this *Politeness) SetMaxPerHost(n int) *Politeness {
+	this.maxPerHost = n
+	return this
+}
+
+// SetDelay sets the minimum time between two requests to one host.
+// The larger of delay and the robots.txt Crawl-delay is used.
+func (this *Politeness) SetDelay(delay time.Duration) *Politeness {
+	this.delay = delay
+	return this
+}
+
+// SetSkipFunc sets the callback reporting requests skipped by policy.
+func (this *Politeness) SetSkipFunc(f SkipFunc) *Politeness {
+	this.onSkip = f
+	return this
+}
+
+// SkippedCount returns how many requests have been skipped by policy.
+func (this *Politeness) SkippedCount() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.skipped
+}
+
+// Allowed reports whether req may be crawled and reports it as skipped if not.
+func (this *Politeness) Allowed(req *request.Request) bool {
+	u, err := url.Parse(req.GetUrl())
+	if err != nil || u.Host == "" {
+		this.skip(req, "bad url")
+		return false
+	}
+
+	if !this.obeyRobots || (u.Scheme != "http" && u.Scheme != "https") {
+		return true
+	}
+
+	if !this.robotsRules(u).Allowed(u.RequestURI()) {
+		this.skip(req, "disallowed by robots.txt")
+		return false
+	}
+	return true
+}
+
+// Acquire blocks until req's host has a free slot and its delay has passed since the last request,
+// or until ctx is done. Every Acquire returning nil must be followed by a Release, and the error of
+// ctx is returned without a slot otherwise.
+func (this *Politeness) Acquire(ctx context.Context, req *request.Request) error {
+	host := hostOf(req)
+	delay := this.hostDelay(req)
+
+	this.locker.Lock()
+	h, ok := this.hosts[host]
+	if !ok {
+		h = &hostState{}
+		this.hosts[host] = h
+	}
+	var stop chan struct{}
+	for this.maxPerHost > 0 && h.active >= this.maxPerHost {
+		if err := ctx.Err(); err != nil {
+			this.locker.Unlock()
+			return err
+		}
+		if stop == nil {
+			// the waiters are woken up when ctx is done
+			stop = make(chan struct{})
+			defer close(stop)
+			go func() {
+				select {
+				case <-ctx.Done():
+					this.locker.Lock()
+					this.cond.Broadcast()
+					this.locker.Unlock()
+				case <-stop:
+				}
+			}()
+		}
+		this.cond.Wait()
+	}
+	h.active++
+
+	now := time.Now()
+	start := h.next
+	if start.Before(now) {
+		start = now
+	}
+	h.next = start.Add(delay)
+	this.locker.Unlock()
+
+	timer := time.NewTimer(start.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		this.Release(req)
+		return ctx.Err()
+	}
+}
+
+// Release frees the host slot taken by Acquire.
+func (this *Politeness) Release(req *request.Request) {
+	host := hostOf(req)
+
+	this.locker.Lock()
+	if h, ok := this.hosts[host]; ok && h.active > 0 {
+		h.active--
+	}
+	this.locker.Unlock()
+	this.cond.Broadcast()
+}
+
+func (this *Politeness) skip(req *request.Request, reason string) {
+	this.locker.Lock()
+	this.skipped++
+	onSkip := this.onSkip
+	this.locker.Unlock()
+
+	mlog.StraceInst().Println("skip crawl : " + req.GetUrl() + " (" + reason + ")")
+	if onSkip != nil {
+		onSkip(req, reason)
+	}
+}
+
+func (this *Politeness) hostDelay(req *request.Request) time.Duration {
+	delay := this.delay
+	if !this.obeyRobots {
+		return delay
+	}
+	u, err := url.Parse(req.GetUrl())
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return delay
+	}
+	if d := this.robotsRules(u).CrawlDelay(); d > delay {
+		delay = d
+	}
+	return delay
+}
+
+// The robotsRules returns the cached rules of u's host, fetching robots.txt on first use.
+// A robots.txt that can not be downloaded or is not 2xx allows everything.
+func (this *Politeness) robotsRules(u *url.URL) *RobotsRules {
+	key := u.Scheme + "://" + u.Host
+
+	this.locker.Lock()
+	entry, ok := this.robots[key]
+	if !ok {
+		entry = &robotsEntry{}
+		this.robots[key] = entry
+	}
+	this.locker.Unlock()
+
+	entry.once.Do(func() {
+		entry.rules = &RobotsRules{}
+
+		header := make(http.Header)
+		header.Set("User-Agent", this.userAgent)
+		req := request.NewRequest(key+"/robots.txt", "text", "robots", "GET", "", header, nil, nil, nil)
+		p := this.pDownloader.Download(req)
+		if p == nil || !p.IsSucc() || p.GetStatusCode() < 200 || p.GetStatusCode() >= 300 {
+			return
+		}
+		entry.rules = ParseRobots(p.GetBodyStr(), this.userAgent)
+	})
+	return entry.rules
+}
+
+func hostOf(req *request.Request) string {
+	u, err := url.Parse(req.GetUrl())
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,154 @@
+package politeness
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"golang.org/x/net/context"
+)
+
+import (
+	"go_spider/core/common/request"
+	"go_spider/core/downloader"
+)
+
+const testRobots = `
+User-agent: otherbot
+Disallow: /
+
+User-agent: *
+Disallow: /private
+Allow: /private/open
+Disallow: /*.pdf$
+Crawl-delay: 0.1
+`
+
+func TestParseRobots(t *testing.T) {
+	r := ParseRobots(testRobots, "go_spider")
+	cases := map[string]bool{
+		"/":                 true,
+		"/private":          false,
+		"/private/a":        false,
+		"/private/open/a":   true,
+		"/doc/a.pdf":        false,
+		"/doc/a.pdf?x=1":    true,
+		"/public?q=private": true,
+	}
+	for path, allowed := range cases {
+		if r.Allowed(path) != allowed {
+			t.Errorf("Allowed(%s) should be %v", path, allowed)
+		}
+	}
+	if r.CrawlDelay() != 100*time.Millisecond {
+		t.Errorf("crawl delay error : %v", r.CrawlDelay())
+	}
+
+	if ParseRobots(testRobots, "OtherBot/1.0").Allowed("/") {
+		t.Error("otherbot group should disallow everything")
+	}
+}
+
+func TestPoliteness(t *testing.T) {
+	var locker sync.Mutex
+	active, maxActive, robotsFetched := 0, 0, 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			locker.Lock()
+			robotsFetched++
+			locker.Unlock()
+			fmt.Fprint(w, testRobots)
+			return
+		}
+		locker.Lock()
+		active++
+		if active > maxActive {
+			maxActive = active
+		}
+		locker.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		locker.Lock()
+		active--
+		locker.Unlock()
+	}))
+	defer ts.Close()
+
+	var skipped []string
+	dl := downloader.NewHttpDownloader()
+	p := NewPoliteness(dl).SetMaxPerHost(1).SetSkipFunc(func(req *request.Request, reason string) {
+		skipped = append(skipped, req.GetUrl())
+	})
+
+	if p.Allowed(request.NewRequest(ts.URL+"/private/a", "text", "", "GET", "", nil, nil, nil, nil)) {
+		t.Error("/private/a should be disallowed")
+	}
+	if len(skipped) != 1 || p.SkippedCount() != 1 {
+		t.Errorf("skip report error : %v", skipped)
+	}
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req := request.NewRequest(fmt.Sprintf("%s/page/%d", ts.URL, i), "text", "", "GET", "", nil, nil, nil, nil)
+			if !p.Allowed(req) {
+				t.Error("page should be allowed")
+				return
+			}
+			if err := p.Acquire(context.Background(), req); err != nil {
+				t.Error(err)
+				return
+			}
+			defer p.Release(req)
+			dl.Download(req)
+		}(i)
+	}
+	wg.Wait()
+
+	if maxActive != 1 {
+		t.Errorf("max concurrent requests per host should be 1 : %d", maxActive)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("crawl delay not honoured : %v", elapsed)
+	}
+	if robotsFetched != 1 {
+		t.Errorf("robots.txt should be fetched once : %d", robotsFetched)
+	}
+}
+
+func TestPolitenessAcquireContext(t *testing.T) {
+	p := NewPoliteness(nil).SetObeyRobots(false).SetDelay(time.Second)
+	req := request.NewRequest("http://a.com/1", "text", "", "GET", "", nil, nil, nil, nil)
+	if err := p.Acquire(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+
+	// waiting for the slot of the host
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := p.Acquire(ctx, req); err != context.DeadlineExceeded {
+		t.Errorf("acquire of a busy host should end with its context : %v", err)
+	}
+	p.Release(req)
+
+	// waiting for the delay of the host
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := p.Acquire(ctx, req); err != context.DeadlineExceeded {
+		t.Errorf("acquire during the delay of a host should end with its context : %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("acquire did not return when its context was done : %v", elapsed)
+	}
+	if active := p.hosts["a.com"].active; active != 0 {
+		t.Errorf("a cancelled acquire should not keep the slot : %d", active)
+	}
+}
@@ -0,0 +1,155 @@
+package politeness
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RobotsRules represents the rules of one robots.txt that apply to a user agent.
+type RobotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+// ParseRobots parses robots.txt content and keeps the group that matches agent.
+// The group whose User-agent is a case insensitive substring of agent is chosen,
+// the "*" group is used when no group matches.
+func ParseRobots(content string, agent string) *RobotsRules {
+	var groups []*robotsGroup
+	var cur *robotsGroup
+	inAgents := false
+
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		pair := strings.SplitN(line, ":", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(pair[0]))
+		value := strings.TrimSpace(pair[1])
+
+		switch key {
+		case "user-agent":
+			if !inAgents {
+				cur = &robotsGroup{}
+				groups = append(groups, cur)
+				inAgents = true
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inAgents = false
+			if cur == nil {
+				continue
+			}
+			// An empty Disallow allows everything.
+			if value == "" {
+				continue
+			}
+			cur.rules = append(cur.rules, robotsRule{allow: key == "allow", pattern: value})
+		case "crawl-delay":
+			inAgents = false
+			if cur == nil {
+				continue
+			}
+			if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+				cur.crawlDelay = time.Duration(secs * float64(time.Second))
+			}
+		}
+	}
+
+	agent = strings.ToLower(agent)
+	var matched, star *robotsGroup
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == "*" {
+				if star == nil {
+					star = g
+				}
+			} else if a != "" && strings.Contains(agent, a) && matched == nil {
+				matched = g
+			}
+		}
+	}
+	if matched == nil {
+		matched = star
+	}
+
+	r := &RobotsRules{}
+	if matched != nil {
+		r.rules = matched.rules
+		r.crawlDelay = matched.crawlDelay
+	}
+	return r
+}
+
+// Allowed reports whether path (with query) may be crawled.
+// The longest matching rule wins and Allow wins a tie.
+func (this *RobotsRules) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+
+	allowed := true
+	longest := -1
+	for _, rule := range this.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if l := len(rule.pattern); l > longest || (l == longest && rule.allow) {
+			longest = l
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// CrawlDelay returns the Crawl-delay of the matched group, 0 if none is set.
+func (this *RobotsRules) CrawlDelay() time.Duration {
+	return this.crawlDelay
+}
+
+// The robotsMatch matches path against a robots.txt pattern supporting "*" and a trailing "$".
+func robotsMatch(pattern string, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = pattern[:len(pattern)-1]
+	}
+
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for _, part := range parts[1:] {
+		i := strings.Index(path[pos:], part)
+		if i < 0 {
+			return false
+		}
+		pos += i + len(part)
+	}
+
+	if !anchored {
+		return true
+	}
+	if len(parts) > 1 && parts[len(parts)-1] == "" {
+		return true
+	}
+	if len(parts) == 1 {
+		return pos == len(path)
+	}
+	return strings.HasSuffix(path, parts[len(parts)-1])
+}
@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"go_spider/core/common/request"
+	"go_spider/core/dedupe"
 )
 
 type Scheduler interface {
@@ -9,3 +10,19 @@ type Scheduler interface {
 	Poll() *request.Request
 	Count() int
 }
+
+// The AckScheduler is a Scheduler whose polled requests are leased until acknowledged.
+// Sipder calls Ack once a polled request has been processed, and requests not acknowledged
+// before their lease expires are given to a worker again.
+type AckScheduler interface {
+	Scheduler
+	Ack(req *request.Request)
+}
+
+// The dupKey is the url a request is deduplicated by, normalized by n unless n is nil.
+func dupKey(n *dedupe.Normalizer, req *request.Request) string {
+	if n == nil {
+		return req.GetUrl()
+	}
+	return n.Normalize(req.GetUrl())
+}
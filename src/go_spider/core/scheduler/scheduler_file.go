@@ -0,0 +1,312 @@
+package scheduler
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+import (
+	"go_spider/core/common/mlog"
+	"go_spider/core/common/request"
+	"go_spider/core/common/util"
+	"go_spider/core/dedupe"
+)
+
+const (
+	fileSchedulerLog      = "queue.log"
+	fileSchedulerSnapshot = "queue.snapshot"
+)
+
+// The FileScheduler is a disk-backed Scheduler whose state survives a restart of the spider.
+// Every Push and Poll is appended to a log file in dir, and the log is compacted into
+// a snapshot of the queued requests and seen url hashes when it grows too long.
+// The records are numbered, so the records already in the snapshot are skipped if a crash
+// happens before the log is truncated.
+// A new FileScheduler opened on the same dir resumes the frontier where the last one stopped.
+//
+// Urls are hashed as they are, or normalized by the dedupe.Normalizer set by SetNormalizer.
+// Unlike QueueScheduler, the seen url hashes are kept after Poll when rmDuplicate is set,
+// so urls crawled before the restart are not crawled again.
+type FileScheduler struct {
+	locker        *sync.Mutex
+	dir           string
+	rm            bool
+	seen          map[string]bool
+	queue         *list.List
+	logFile       *os.File
+	logCount      int
+	seq           uint64
+	snapshotEvery int
+	normalizer    *dedupe.Normalizer
+}
+
+type fileSchedulerRecord struct {
+	// The Op is "push" or "poll".
+	Op  string           `json:"op"`
+	Seq uint64           `json:"seq"`
+	Req *request.Request `json:"req,omitempty"`
+}
+
+type fileSchedulerState struct {
+	// The Seq is the number of the last log record in the state.
+	Seq   uint64             `json:"seq"`
+	Queue []*request.Request `json:"queue"`
+	Seen  []string           `json:"seen"`
+}
+
+// NewFileScheduler opens or creates the scheduler state in directory dir.
+func NewFileScheduler(dir string, rmDuplicate bool) *FileScheduler {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		panic("FileScheduler dir '" + dir + "' create failed.")
+	}
+
+	this := &FileScheduler{
+		locker:        new(sync.Mutex),
+		dir:           dir,
+		rm:            rmDuplicate,
+		seen:          make(map[string]bool),
+		queue:         list.New(),
+		snapshotEvery: 1000,
+	}
+	this.loadSnapshot()
+	end := this.replayLog()
+
+	logFile, err := os.OpenFile(this.path(fileSchedulerLog), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		panic("FileScheduler log '" + this.path(fileSchedulerLog) + "' open failed.")
+	}
+	// cut a broken last record so the next records are not written after it
+	if err = logFile.Truncate(end); err != nil {
+		panic("FileScheduler log '" + this.path(fileSchedulerLog) + "' truncate failed.")
+	}
+	this.logFile = logFile
+	return this
+}
+
+// SetSnapshotEvery sets how many log records are written before the log is compacted into the snapshot.
+func (this *FileScheduler) SetSnapshotEvery(n int) *FileScheduler {
+	this.locker.Lock()
+	this.snapshotEvery = n
+	this.locker.Unlock()
+	return this
+}
+
+// SetNormalizer sets the Normalizer of urls, nil (the default) dedupes by the raw url.
+// Urls already seen keep the hashes of the normalizer they were pushed with.
+func (this *FileScheduler) SetNormalizer(n *dedupe.Normalizer) *FileScheduler {
+	this.locker.Lock()
+	this.normalizer = n
+	this.locker.Unlock()
+	return this
+}
+
+func (this *FileScheduler) Push(req *request.Request) {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	var key string
+	if this.rm {
+		key = util.MakeHash(dupKey(this.normalizer, req))
+		if this.seen[key] {
+			return
+		}
+	}
+
+	if !this.appendLog(&fileSchedulerRecord{Op: "push", Req: req}) {
+		return
+	}
+	this.queue.PushBack(req)
+	if this.rm {
+		this.seen[key] = true
+	}
+	this.compact()
+}
+
+func (this *FileScheduler) Poll() *request.Request {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if this.queue.Len() <= 0 {
+		return nil
+	}
+
+	if !this.appendLog(&fileSchedulerRecord{Op: "poll"}) {
+		return nil
+	}
+	e := this.queue.Front()
+	this.queue.Remove(e)
+	this.compact()
+	return e.Value.(*request.Request)
+}
+
+func (this *FileScheduler) Count() int {
+	this.locker.Lock()
+	len := this.queue.Len()
+	this.locker.Unlock()
+	return len
+}
+
+// Close writes a final snapshot and closes the log file.
+func (this *FileScheduler) Close() error {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	if err := this.snapshot(); err != nil {
+		return err
+	}
+	return this.logFile.Close()
+}
+
+func (this *FileScheduler) path(name string) string {
+	return filepath.Join(this.dir, name)
+}
+
+// The appendLog writes one record to the log before the change is applied in memory.
+func (this *FileScheduler) appendLog(record *fileSchedulerRecord) bool {
+	record.Seq = this.seq + 1
+	b, err := json.Marshal(record)
+	if err != nil {
+		mlog.LogInst().LogError("FileScheduler encode error : " + err.Error())
+		return false
+	}
+
+	if _, err = this.logFile.Write(append(b, '\n')); err != nil {
+		mlog.LogInst().LogError("FileScheduler log write error : " + err.Error())
+		return false
+	}
+
+	this.seq = record.Seq
+	this.logCount++
+	return true
+}
+
+// The compact writes a snapshot once the log holds snapshotEvery records.
+func (this *FileScheduler) compact() {
+	if this.snapshotEvery <= 0 || this.logCount < this.snapshotEvery {
+		return
+	}
+	if err := this.snapshot(); err != nil {
+		mlog.LogInst().LogError("FileScheduler snapshot error : " + err.Error())
+	}
+}
+
+// The snapshot writes the whole state to the snapshot file and truncates the log.
+// The snapshot is written to a temporary file first so a crash never leaves a half written snapshot,
+// and a log left by a crash before the truncate only holds records older than the snapshot Seq.
+func (this *FileScheduler) snapshot() error {
+	state := fileSchedulerState{Seq: this.seq, Queue: make([]*request.Request, 0, this.queue.Len()), Seen: make([]string, 0, len(this.seen))}
+	for e := this.queue.Front(); e != nil; e = e.Next() {
+		state.Queue = append(state.Queue, e.Value.(*request.Request))
+	}
+	for key := range this.seen {
+		state.Seen = append(state.Seen, key)
+	}
+
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp := this.path(fileSchedulerSnapshot + ".tmp")
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(b); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, this.path(fileSchedulerSnapshot)); err != nil {
+		return err
+	}
+
+	if err = this.logFile.Truncate(0); err != nil {
+		return err
+	}
+	this.logCount = 0
+	return nil
+}
+
+func (this *FileScheduler) loadSnapshot() {
+	b, err := ioutil.ReadFile(this.path(fileSchedulerSnapshot))
+	if err != nil {
+		return
+	}
+
+	var state fileSchedulerState
+	if err = json.Unmarshal(b, &state); err != nil {
+		mlog.LogInst().LogError("FileScheduler snapshot decode error : " + err.Error())
+		return
+	}
+
+	this.seq = state.Seq
+	for _, req := range state.Queue {
+		this.queue.PushBack(req)
+	}
+	for _, key := range state.Seen {
+		this.seen[key] = true
+	}
+}
+
+// The replayLog applies the records newer than the snapshot and returns the offset
+// of the end of the last good record. A broken last line left by a crash in the middle of
+// a write is ignored, as is everything after it.
+func (this *FileScheduler) replayLog() int64 {
+	f, err := os.Open(this.path(fileSchedulerLog))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	var end int64
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			// a last line without its newline was not completely written
+			if len(line) > 0 {
+				mlog.LogInst().LogError("FileScheduler log ends with a broken record, it is discarded")
+			}
+			break
+		}
+
+		var record fileSchedulerRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			mlog.LogInst().LogError("FileScheduler log decode error : " + err.Error())
+			break
+		}
+		end += int64(len(line))
+		this.logCount++
+		if record.Seq <= this.seq {
+			// already in the snapshot
+			continue
+		}
+		this.seq = record.Seq
+
+		switch record.Op {
+		case "push":
+			if record.Req == nil {
+				continue
+			}
+			this.queue.PushBack(record.Req)
+			if this.rm {
+				this.seen[util.MakeHash(dupKey(this.normalizer, record.Req))] = true
+			}
+		case "poll":
+			if e := this.queue.Front(); e != nil {
+				this.queue.Remove(e)
+			}
+		}
+	}
+	return end
+}
@@ -8,13 +8,23 @@ import (
 
 import (
 	"go_spider/core/common/request"
+	"go_spider/core/dedupe"
 )
 
+// The QueueScheduler dedupes queued requests by their urls when rmDuplicate is set.
+// The urls are normalized by a dedupe.Normalizer set by SetNormalizer.
 type QueueScheduler struct {
-	locker *sync.Mutex
-	rm     bool
-	rmKey  map[[md5.Size]byte]*list.Element
-	queue  *list.List
+	locker     *sync.Mutex
+	rm         bool
+	rmKey      map[[md5.Size]byte]*list.Element
+	queue      *list.List
+	normalizer *dedupe.Normalizer
+}
+
+// The queueEntry is a queued request with the key it is deduplicated by, computed at Push.
+type queueEntry struct {
+	req *request.Request
+	key [md5.Size]byte
 }
 
 func NewQueueScheduler(rmDuplicate bool) *QueueScheduler {
@@ -24,18 +34,26 @@ func NewQueueScheduler(rmDuplicate bool) *QueueScheduler {
 	return &QueueScheduler{rm: rmDuplicate, queue: queue, rmKey: rmKey, locker: locker}
 }
 
+// SetNormalizer sets the Normalizer of urls, nil (the default) dedupes by the raw url.
+func (this *QueueScheduler) SetNormalizer(n *dedupe.Normalizer) *QueueScheduler {
+	this.locker.Lock()
+	this.normalizer = n
+	this.locker.Unlock()
+	return this
+}
+
 func (this *QueueScheduler) Push(req *request.Request) {
 	this.locker.Lock()
 	var key [md5.Size]byte
 	if this.rm {
-		key = md5.Sum([]byte(req.GetUrl()))
+		key = md5.Sum([]byte(dupKey(this.normalizer, req)))
 		if _, ok := this.rmKey[key]; ok {
 			this.locker.Unlock()
 			return
 		}
 	}
 
-	e := this.queue.PushBack(req)
+	e := this.queue.PushBack(&queueEntry{req: req, key: key})
 	if this.rm {
 		this.rmKey[key] = e
 	}
@@ -49,14 +67,13 @@ func (this *QueueScheduler) Poll() *request.Request {
 	}
 
 	e := this.queue.Front()
-	req := e.Value.(*request.Request)
-	key := md5.Sum([]byte(req.GetUrl()))
+	entry := e.Value.(*queueEntry)
 	this.queue.Remove(e)
 	if this.rm {
-		delete(this.rmKey, key)
+		delete(this.rmKey, entry.key)
 	}
 	this.locker.Unlock()
-	return req
+	return entry.req
 }
 func (this *QueueScheduler) Count() int {
 	this.locker.Lock()
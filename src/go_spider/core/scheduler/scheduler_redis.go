@@ -0,0 +1,300 @@
+package scheduler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/garyburd/redigo/redis"
+)
+
+import (
+	"go_spider/core/common/mlog"
+	"go_spider/core/common/request"
+	"go_spider/core/common/util"
+	"go_spider/core/dedupe"
+)
+
+// The RedisScheduler keeps the frontier in Redis so that many Sipder processes share it.
+// Requests are polled by priority, then in push order.
+// A polled request is leased to the worker until it is acknowledged by Ack, and a request whose lease
+// expires, because its worker died, is put back into the queue by the next Poll of any worker.
+// Every lease is a member "id/token" with a token unique to the lease, so the late Ack of a worker
+// whose lease expired does not end the lease of the worker holding the request now.
+//
+// The keys used under name are:
+//
+//	name:seq     counter giving every request an id
+//	name:data    hash of request json by id
+//	name:queue   sorted set of waiting ids
+//	name:leases  sorted set of leases "id/token" by lease deadline
+//	name:seen    set of url hashes when rmDuplicate is set
+//
+// Commands and MULTI/EXEC transactions are used, but no scripts, so any Redis from 3.0.2 works.
+type RedisScheduler struct {
+	pool         *redis.Pool
+	name         string
+	rm           bool
+	leaseTimeout time.Duration
+	normalizer   *dedupe.Normalizer
+
+	locker *sync.Mutex
+	// The worker is the random prefix of the lease tokens of this scheduler and leases counts them.
+	worker string
+	leases int64
+	leased map[*request.Request]string
+}
+
+// The pollCandidates is how many waiting ids a Poll tries to lease before giving up.
+const pollCandidates = 10
+
+// NewRedisScheduler uses the keys under name in the Redis of pool.
+// The lease timeout is 5 minutes and should be longer than a request takes to be processed.
+func NewRedisScheduler(pool *redis.Pool, name string, rmDuplicate bool) *RedisScheduler {
+	worker := make([]byte, 8)
+	rand.Read(worker)
+	return &RedisScheduler{
+		pool:         pool,
+		name:         name,
+		rm:           rmDuplicate,
+		leaseTimeout: 5 * time.Minute,
+		locker:       new(sync.Mutex),
+		worker:       hex.EncodeToString(worker),
+		leased:       make(map[*request.Request]string),
+	}
+}
+
+// SetLeaseTimeout sets how long a polled request is held by its worker without Ack.
+func (this *RedisScheduler) SetLeaseTimeout(d time.Duration) *RedisScheduler {
+	this.leaseTimeout = d
+	return this
+}
+
+// SetNormalizer sets the Normalizer of urls, nil (the default) dedupes by the raw url.
+// Every worker sharing the keys should use the same normalizer.
+func (this *RedisScheduler) SetNormalizer(n *dedupe.Normalizer) *RedisScheduler {
+	this.normalizer = n
+	return this
+}
+
+// Push queues req. The id is taken before the url is marked seen, and the url is unmarked when
+// queueing fails, so a url is never seen without being queued.
+func (this *RedisScheduler) Push(req *request.Request) {
+	conn := this.pool.Get()
+	defer conn.Close()
+
+	b, err := json.Marshal(req)
+	if err != nil {
+		mlog.LogInst().LogError("RedisScheduler encode error : " + err.Error())
+		return
+	}
+
+	seq, err := redis.Int64(conn.Do("INCR", this.key("seq")))
+	if err != nil {
+		mlog.LogInst().LogError("RedisScheduler push error : " + err.Error())
+		return
+	}
+
+	var hash string
+	if this.rm {
+		hash = util.MakeHash(dupKey(this.normalizer, req))
+		added, err := redis.Int(conn.Do("SADD", this.key("seen"), hash))
+		if err != nil {
+			mlog.LogInst().LogError("RedisScheduler push error : " + err.Error())
+			return
+		}
+		if added == 0 {
+			return
+		}
+	}
+
+	id := strconv.FormatInt(seq, 10)
+	conn.Send("MULTI")
+	conn.Send("HSET", this.key("data"), id, b)
+	conn.Send("ZADD", this.key("queue"), queueScore(req.GetPriority(), seq), id)
+	if _, err = conn.Do("EXEC"); err != nil {
+		mlog.LogInst().LogError("RedisScheduler push error : " + err.Error())
+		if this.rm {
+			if _, err = conn.Do("SREM", this.key("seen"), hash); err != nil {
+				mlog.LogInst().LogError("RedisScheduler unmark seen error : " + err.Error())
+			}
+		}
+	}
+}
+
+func (this *RedisScheduler) Poll() *request.Request {
+	conn := this.pool.Get()
+	defer conn.Close()
+
+	this.requeueExpired(conn)
+
+	ids, err := redis.Strings(conn.Do("ZRANGE", this.key("queue"), 0, pollCandidates-1))
+	if err != nil {
+		mlog.LogInst().LogError("RedisScheduler poll error : " + err.Error())
+		return nil
+	}
+
+	deadline := time.Now().Add(this.leaseTimeout).UnixNano()
+	for _, id := range ids {
+		// The lease is taken before the id leaves the queue, so the request is never lost by a crash.
+		// Removing the id from the queue claims it, and an id claimed by another worker is skipped.
+		lease := this.newLease(id)
+		if _, err := conn.Do("ZADD", this.key("leases"), deadline, lease); err != nil {
+			mlog.LogInst().LogError("RedisScheduler poll error : " + err.Error())
+			return nil
+		}
+		claimed, err := redis.Int(conn.Do("ZREM", this.key("queue"), id))
+		if err != nil || claimed == 0 {
+			conn.Do("ZREM", this.key("leases"), lease)
+			if err != nil {
+				mlog.LogInst().LogError("RedisScheduler poll error : " + err.Error())
+				return nil
+			}
+			continue
+		}
+
+		b, err := redis.Bytes(conn.Do("HGET", this.key("data"), id))
+		req := new(request.Request)
+		if err == nil {
+			err = json.Unmarshal(b, req)
+		}
+		if err != nil {
+			// acknowledged by another worker after its lease expired
+			conn.Do("ZREM", this.key("leases"), lease)
+			continue
+		}
+
+		this.locker.Lock()
+		this.leased[req] = lease
+		this.locker.Unlock()
+		return req
+	}
+	return nil
+}
+
+// Ack ends the lease of a polled request and removes it from Redis.
+// Nothing is removed when the lease has expired, as the request may be held by another worker.
+func (this *RedisScheduler) Ack(req *request.Request) {
+	this.locker.Lock()
+	lease, ok := this.leased[req]
+	delete(this.leased, req)
+	this.locker.Unlock()
+	if !ok {
+		return
+	}
+
+	conn := this.pool.Get()
+	defer conn.Close()
+
+	// only the worker whose lease is still there removes it
+	removed, err := redis.Int(conn.Do("ZREM", this.key("leases"), lease))
+	if err != nil {
+		mlog.LogInst().LogError("RedisScheduler ack error : " + err.Error())
+		return
+	}
+	if removed == 0 {
+		mlog.StraceInst().Println("ack of an expired lease : " + req.GetUrl())
+		return
+	}
+	conn.Do("HDEL", this.key("data"), leaseId(lease))
+}
+
+// Count returns the count of waiting requests, leased requests are not counted.
+func (this *RedisScheduler) Count() int {
+	conn := this.pool.Get()
+	defer conn.Close()
+
+	n, err := redis.Int(conn.Do("ZCARD", this.key("queue")))
+	if err != nil {
+		mlog.LogInst().LogError("RedisScheduler count error : " + err.Error())
+		return 0
+	}
+	return n
+}
+
+// The requeueExpired puts the requests whose lease has expired back into the queue.
+// A lease is moved to the queue in a transaction that fails when the leases change meanwhile,
+// so a lease requeued or acknowledged by another worker is not queued again, and is retried by the next Poll.
+func (this *RedisScheduler) requeueExpired(conn redis.Conn) {
+	leases, err := redis.Strings(conn.Do("ZRANGEBYSCORE", this.key("leases"), "-inf", time.Now().UnixNano(), "LIMIT", 0, 100))
+	if err != nil {
+		mlog.LogInst().LogError("RedisScheduler requeue error : " + err.Error())
+		return
+	}
+
+	for _, lease := range leases {
+		if err := this.requeue(conn, lease); err != nil && err != redis.ErrNil {
+			mlog.LogInst().LogError("RedisScheduler requeue error : " + err.Error())
+		}
+	}
+}
+
+// The requeue moves lease back into the queue, redis.ErrNil is returned when the leases changed.
+func (this *RedisScheduler) requeue(conn redis.Conn, lease string) error {
+	if _, err := conn.Do("WATCH", this.key("leases")); err != nil {
+		return err
+	}
+	defer conn.Do("UNWATCH")
+
+	if _, err := redis.Float64(conn.Do("ZSCORE", this.key("leases"), lease)); err != nil {
+		// ended meanwhile
+		return err
+	}
+	id := leaseId(lease)
+	b, err := redis.Bytes(conn.Do("HGET", this.key("data"), id))
+	if err == redis.ErrNil {
+		// acknowledged by another worker
+		_, err = conn.Do("ZREM", this.key("leases"), lease)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	req := new(request.Request)
+	if err = json.Unmarshal(b, req); err != nil {
+		return err
+	}
+	seq, _ := strconv.ParseInt(id, 10, 64)
+
+	conn.Send("MULTI")
+	conn.Send("ZADD", this.key("queue"), queueScore(req.GetPriority(), seq), id)
+	conn.Send("ZREM", this.key("leases"), lease)
+	if _, err = redis.Values(conn.Do("EXEC")); err != nil {
+		return err
+	}
+	mlog.StraceInst().Println("requeue expired request : " + req.GetUrl())
+	return nil
+}
+
+// The newLease returns the lease member of id with a token unique to this lease.
+func (this *RedisScheduler) newLease(id string) string {
+	this.locker.Lock()
+	this.leases++
+	n := this.leases
+	this.locker.Unlock()
+	return id + "/" + this.worker + "." + strconv.FormatInt(n, 10)
+}
+
+// The leaseId returns the request id of a lease member.
+func leaseId(lease string) string {
+	if i := strings.IndexByte(lease, '/'); i >= 0 {
+		return lease[:i]
+	}
+	return lease
+}
+
+func (this *RedisScheduler) key(name string) string {
+	return this.name + ":" + name
+}
+
+// The queueScore orders by priority descending, then by push order.
+// It is exact while sequences stay below 1e10 and priorities between -1e5 and 1e5.
+func queueScore(priority int, seq int64) string {
+	return strconv.FormatFloat(-float64(priority)*1e10+float64(seq), 'f', -1, 64)
+}
@@ -0,0 +1,350 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/garyburd/redigo/redis"
+)
+
+import (
+	"go_spider/core/common/request"
+)
+
+// The fakeRedis is an in-memory stand-in for a Redis server supporting the commands of RedisScheduler.
+// The versions count the writes of every key for WATCH, and failExec makes every EXEC fail.
+type fakeRedis struct {
+	locker   *sync.Mutex
+	strings  map[string]int64
+	hashes   map[string]map[string][]byte
+	zsets    map[string]map[string]float64
+	sets     map[string]map[string]bool
+	versions map[string]int
+	failExec bool
+}
+
+// The fakeRedisConn runs the commands sent by Send at once, except in a transaction where they are
+// queued until EXEC. Replies are returned by Do only.
+type fakeRedisConn struct {
+	r       *fakeRedis
+	watched map[string]int
+	multi   [][]interface{}
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{
+		locker:   new(sync.Mutex),
+		strings:  make(map[string]int64),
+		hashes:   make(map[string]map[string][]byte),
+		zsets:    make(map[string]map[string]float64),
+		sets:     make(map[string]map[string]bool),
+		versions: make(map[string]int),
+	}
+}
+
+func (this *fakeRedis) pool() *redis.Pool {
+	return &redis.Pool{MaxIdle: 1, Dial: func() (redis.Conn, error) { return &fakeRedisConn{r: this}, nil }}
+}
+
+func (this *fakeRedisConn) Close() error { return nil }
+func (this *fakeRedisConn) Err() error   { return nil }
+func (this *fakeRedisConn) Flush() error { return nil }
+func (this *fakeRedisConn) Receive() (reply interface{}, err error) {
+	return nil, errors.New("not supported")
+}
+
+func (this *fakeRedisConn) Send(cmd string, args ...interface{}) error {
+	_, err := this.Do(cmd, args...)
+	return err
+}
+
+func (this *fakeRedisConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	r := this.r
+	r.locker.Lock()
+	defer r.locker.Unlock()
+
+	switch strings.ToUpper(cmd) {
+	case "WATCH":
+		if this.watched == nil {
+			this.watched = make(map[string]int)
+		}
+		for _, key := range args {
+			this.watched[fmt.Sprint(key)] = r.versions[fmt.Sprint(key)]
+		}
+		return "OK", nil
+	case "UNWATCH":
+		this.watched = nil
+		return "OK", nil
+	case "MULTI":
+		this.multi = [][]interface{}{}
+		return "OK", nil
+	case "DISCARD":
+		this.multi, this.watched = nil, nil
+		return "OK", nil
+	case "EXEC":
+		multi, watched := this.multi, this.watched
+		this.multi, this.watched = nil, nil
+		if r.failExec {
+			return nil, errors.New("exec failed")
+		}
+		for key, version := range watched {
+			if r.versions[key] != version {
+				return nil, nil
+			}
+		}
+		var replies []interface{}
+		for _, c := range multi {
+			reply, err := r.do(c[0].(string), c[1:]...)
+			if err != nil {
+				return nil, err
+			}
+			replies = append(replies, reply)
+		}
+		return replies, nil
+	}
+	if this.multi != nil {
+		this.multi = append(this.multi, append([]interface{}{cmd}, args...))
+		return "QUEUED", nil
+	}
+	return r.do(cmd, args...)
+}
+
+func (r *fakeRedis) do(cmd string, args ...interface{}) (interface{}, error) {
+	a := make([]string, len(args))
+	for i, arg := range args {
+		if b, ok := arg.([]byte); ok {
+			a[i] = string(b)
+		} else {
+			a[i] = fmt.Sprint(arg)
+		}
+	}
+
+	switch strings.ToUpper(cmd) {
+	case "INCR", "SADD", "SREM", "HSET", "HDEL", "ZADD", "ZREM":
+		r.versions[a[0]]++
+	}
+
+	switch strings.ToUpper(cmd) {
+	case "":
+		return nil, nil
+	case "INCR":
+		r.strings[a[0]]++
+		return r.strings[a[0]], nil
+	case "SADD":
+		if r.sets[a[0]] == nil {
+			r.sets[a[0]] = make(map[string]bool)
+		}
+		if r.sets[a[0]][a[1]] {
+			return int64(0), nil
+		}
+		r.sets[a[0]][a[1]] = true
+		return int64(1), nil
+	case "SREM":
+		if !r.sets[a[0]][a[1]] {
+			return int64(0), nil
+		}
+		delete(r.sets[a[0]], a[1])
+		return int64(1), nil
+	case "HSET":
+		if r.hashes[a[0]] == nil {
+			r.hashes[a[0]] = make(map[string][]byte)
+		}
+		r.hashes[a[0]][a[1]] = []byte(a[2])
+		return int64(1), nil
+	case "HGET":
+		if v, ok := r.hashes[a[0]][a[1]]; ok {
+			return v, nil
+		}
+		return nil, nil
+	case "HDEL":
+		delete(r.hashes[a[0]], a[1])
+		return int64(1), nil
+	case "ZADD":
+		if r.zsets[a[0]] == nil {
+			r.zsets[a[0]] = make(map[string]float64)
+		}
+		nx := a[1] == "NX"
+		if nx {
+			a = append(a[:1], a[2:]...)
+		}
+		if _, ok := r.zsets[a[0]][a[2]]; ok {
+			if !nx {
+				r.zsets[a[0]][a[2]], _ = strconv.ParseFloat(a[1], 64)
+			}
+			return int64(0), nil
+		}
+		r.zsets[a[0]][a[2]], _ = strconv.ParseFloat(a[1], 64)
+		return int64(1), nil
+	case "ZREM":
+		if _, ok := r.zsets[a[0]][a[1]]; !ok {
+			return int64(0), nil
+		}
+		delete(r.zsets[a[0]], a[1])
+		return int64(1), nil
+	case "ZSCORE":
+		if v, ok := r.zsets[a[0]][a[1]]; ok {
+			return []byte(strconv.FormatFloat(v, 'f', -1, 64)), nil
+		}
+		return nil, nil
+	case "ZCARD":
+		return int64(len(r.zsets[a[0]])), nil
+	case "ZRANGE", "ZRANGEBYSCORE":
+		var members []string
+		for m := range r.zsets[a[0]] {
+			members = append(members, m)
+		}
+		z := r.zsets[a[0]]
+		sort.Slice(members, func(i, j int) bool {
+			if z[members[i]] != z[members[j]] {
+				return z[members[i]] < z[members[j]]
+			}
+			return members[i] < members[j]
+		})
+
+		var reply []interface{}
+		if strings.ToUpper(cmd) == "ZRANGE" {
+			start, _ := strconv.Atoi(a[1])
+			stop, _ := strconv.Atoi(a[2])
+			for i := start; i <= stop && i < len(members); i++ {
+				reply = append(reply, []byte(members[i]))
+			}
+			return reply, nil
+		}
+		max, _ := strconv.ParseFloat(a[2], 64)
+		for _, m := range members {
+			if z[m] <= max {
+				reply = append(reply, []byte(m))
+			}
+		}
+		return reply, nil
+	}
+	return nil, errors.New("unknown command " + cmd)
+}
+
+func TestRedisScheduler(t *testing.T) {
+	fake := newFakeRedis()
+	worker1 := NewRedisScheduler(fake.pool(), "test", true)
+	worker2 := NewRedisScheduler(fake.pool(), "test", true).SetLeaseTimeout(50 * time.Millisecond)
+
+	worker1.Push(request.NewRequest("http://a.com", "html", "", "GET", "", nil, nil, nil, nil))
+	worker1.Push(request.NewRequest("http://b.com", "html", "", "GET", "", nil, nil, nil, nil).SetPriority(5))
+	worker2.Push(request.NewRequest("http://a.com", "html", "", "GET", "", nil, nil, nil, nil))
+	worker2.Push(request.NewRequest("http://c.com", "html", "", "GET", "", nil, nil, nil, nil))
+	if worker1.Count() != 3 {
+		t.Errorf("count error : %d", worker1.Count())
+	}
+
+	// worker2 polls the request with the highest priority and dies without Ack
+	r := worker2.Poll()
+	if r == nil || r.GetUrl() != "http://b.com" || r.GetPriority() != 5 {
+		t.Errorf("priority poll error : %v", r)
+	}
+	r = worker1.Poll()
+	if r == nil || r.GetUrl() != "http://a.com" {
+		t.Errorf("poll error : %v", r)
+	}
+	worker1.Ack(r)
+	if worker1.Count() != 1 {
+		t.Errorf("count after poll error : %d", worker1.Count())
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	r = worker1.Poll()
+	if r == nil || r.GetUrl() != "http://b.com" {
+		t.Errorf("expired lease should be requeued : %v", r)
+	}
+	worker1.Ack(r)
+	r = worker1.Poll()
+	if r == nil || r.GetUrl() != "http://c.com" {
+		t.Errorf("poll error : %v", r)
+	}
+	worker1.Ack(r)
+
+	if worker1.Poll() != nil || worker1.Count() != 0 {
+		t.Error("queue should be empty")
+	}
+	if len(fake.hashes["test:data"]) != 0 || len(fake.zsets["test:leases"]) != 0 {
+		t.Error("acknowledged requests should be removed")
+	}
+}
+
+func TestRedisSchedulerLateAck(t *testing.T) {
+	fake := newFakeRedis()
+	worker1 := NewRedisScheduler(fake.pool(), "test", true).SetLeaseTimeout(50 * time.Millisecond)
+	worker2 := NewRedisScheduler(fake.pool(), "test", true)
+
+	worker1.Push(request.NewRequest("http://a.com", "html", "", "GET", "", nil, nil, nil, nil))
+	r1 := worker1.Poll()
+	time.Sleep(100 * time.Millisecond)
+	r2 := worker2.Poll()
+	if r1 == nil || r2 == nil || r2.GetUrl() != "http://a.com" {
+		t.Fatalf("expired lease should be requeued : %v %v", r1, r2)
+	}
+
+	// the late Ack of worker1 keeps the lease and the request of worker2
+	worker1.Ack(r1)
+	if len(fake.hashes["test:data"]) != 1 || len(fake.zsets["test:leases"]) != 1 {
+		t.Error("a late ack should not remove the request leased by another worker")
+	}
+	worker2.Ack(r2)
+	if len(fake.hashes["test:data"]) != 0 || len(fake.zsets["test:leases"]) != 0 {
+		t.Error("acknowledged requests should be removed")
+	}
+}
+
+func TestRedisSchedulerRequeue(t *testing.T) {
+	fake := newFakeRedis()
+	worker := NewRedisScheduler(fake.pool(), "test", true).SetLeaseTimeout(time.Millisecond)
+	worker.Push(request.NewRequest("http://a.com", "html", "", "GET", "", nil, nil, nil, nil))
+	worker.Poll()
+	var lease string
+	for lease = range fake.zsets["test:leases"] {
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	conn := fake.pool().Get()
+	defer conn.Close()
+
+	// the leases change between the WATCH and the EXEC of the requeue
+	conn.Do("WATCH", "test:leases")
+	fake.pool().Get().Do("ZADD", "test:leases", 1, "other")
+	conn.Do("MULTI")
+	conn.Do("ZADD", "test:queue", 1, "x")
+	if reply, _ := conn.Do("EXEC"); reply != nil {
+		t.Error("exec should fail when a watched key changes")
+	}
+	fake.pool().Get().Do("ZREM", "test:leases", "other")
+
+	if err := worker.requeue(conn, lease); err != nil {
+		t.Fatal(err)
+	}
+	// a lease requeued by another worker is not queued again
+	if err := worker.requeue(conn, lease); err != redis.ErrNil || worker.Count() != 1 {
+		t.Errorf("a requeued lease should not be requeued again : %v %d", err, worker.Count())
+	}
+}
+
+func TestRedisSchedulerPushFailure(t *testing.T) {
+	fake := newFakeRedis()
+	worker := NewRedisScheduler(fake.pool(), "test", true)
+
+	fake.failExec = true
+	worker.Push(request.NewRequest("http://a.com", "html", "", "GET", "", nil, nil, nil, nil))
+	if len(fake.sets["test:seen"]) != 0 || worker.Count() != 0 {
+		t.Error("a url that failed to be queued should not be seen")
+	}
+
+	fake.failExec = false
+	worker.Push(request.NewRequest("http://a.com", "html", "", "GET", "", nil, nil, nil, nil))
+	if worker.Count() != 1 {
+		t.Error("a url that failed to be queued should be queued by the next push")
+	}
+}
@@ -2,11 +2,15 @@ package scheduler
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
 import (
 	"go_spider/core/common/request"
+	"go_spider/core/dedupe"
 )
 
 func TestQueueScheduler(t *testing.T) {
@@ -54,3 +58,156 @@ func TestQueueScheduler(t *testing.T) {
 	}
 	fmt.Printf("%v\n", r1)
 }
+
+func TestFileScheduler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_scheduler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r1 := request.NewRequest("http://baidu.com", "html", "", "GET", "", nil, nil, nil, nil)
+	r2 := request.NewRequest("http://qq.com", "html", "tag", "GET", "", nil, nil, nil, map[string]interface{}{"depth": 1})
+	r3 := request.NewRequest("http://sina.com", "html", "", "GET", "", nil, nil, nil, nil)
+
+	s := NewFileScheduler(dir, true).SetSnapshotEvery(3)
+	s.Push(r1)
+	s.Push(r2)
+	s.Push(r1)
+	s.Push(r3)
+	if s.Count() != 3 {
+		t.Error("count error")
+	}
+	if r := s.Poll(); r == nil || r.GetUrl() != "http://baidu.com" {
+		t.Error("poll error")
+	}
+
+	// resume without Close as after a crash
+	s = NewFileScheduler(dir, true)
+	if s.Count() != 2 {
+		t.Errorf("resume count error : %d", s.Count())
+	}
+	s.Push(r1)
+	if s.Count() != 2 {
+		t.Error("seen url pushed again after resume")
+	}
+
+	r := s.Poll()
+	if r == nil || r.GetUrl() != "http://qq.com" || r.GetUrlTag() != "tag" {
+		t.Errorf("resume poll error : %v", r)
+	}
+	fmt.Printf("%v\n", r.GetMeta())
+	if err := s.Close(); err != nil {
+		t.Error(err)
+	}
+
+	s = NewFileScheduler(dir, true)
+	if r := s.Poll(); r == nil || r.GetUrl() != "http://sina.com" {
+		t.Error("poll after close error")
+	}
+	if s.Poll() != nil {
+		t.Error("queue should be empty")
+	}
+	s.Close()
+}
+
+func TestFileSchedulerTornLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_scheduler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := NewFileScheduler(dir, true).SetSnapshotEvery(0)
+	s.Push(request.NewRequest("http://baidu.com", "html", "", "GET", "", nil, nil, nil, nil))
+	s.logFile.Close()
+
+	// a crash in the middle of a write
+	f, err := os.OpenFile(filepath.Join(dir, fileSchedulerLog), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"pu`)
+	f.Close()
+
+	s = NewFileScheduler(dir, true).SetSnapshotEvery(0)
+	if s.Count() != 1 {
+		t.Errorf("torn log count error : %d", s.Count())
+	}
+	s.Push(request.NewRequest("http://qq.com", "html", "", "GET", "", nil, nil, nil, nil))
+	s.Push(request.NewRequest("http://sina.com", "html", "", "GET", "", nil, nil, nil, nil))
+	s.logFile.Close()
+
+	s = NewFileScheduler(dir, true)
+	if s.Count() != 3 {
+		t.Errorf("records written after a torn record are lost : %d", s.Count())
+	}
+	s.Close()
+}
+
+func TestFileSchedulerSnapshotCrash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_scheduler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := NewFileScheduler(dir, true).SetSnapshotEvery(0)
+	s.Push(request.NewRequest("http://baidu.com", "html", "", "GET", "", nil, nil, nil, nil))
+	s.Push(request.NewRequest("http://qq.com", "html", "", "GET", "", nil, nil, nil, nil))
+	s.Poll()
+	log, err := ioutil.ReadFile(filepath.Join(dir, fileSchedulerLog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// a crash after the snapshot is written but before the log is truncated
+	if err := ioutil.WriteFile(filepath.Join(dir, fileSchedulerLog), log, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s = NewFileScheduler(dir, true)
+	if s.Count() != 1 {
+		t.Errorf("records of the snapshot replayed again : %d", s.Count())
+	}
+	if r := s.Poll(); r == nil || r.GetUrl() != "http://qq.com" {
+		t.Errorf("poll after snapshot crash error : %v", r)
+	}
+	s.Push(request.NewRequest("http://sina.com", "html", "", "GET", "", nil, nil, nil, nil))
+	s.logFile.Close()
+
+	s = NewFileScheduler(dir, true)
+	if r := s.Poll(); r == nil || r.GetUrl() != "http://sina.com" {
+		t.Errorf("poll of a record written after the snapshot error : %v", r)
+	}
+	s.Close()
+}
+
+func TestQueueSchedulerNormalize(t *testing.T) {
+	s := NewQueueScheduler(true).SetNormalizer(dedupe.NewNormalizer())
+	s.Push(request.NewRequest("http://a.com/x?b=1&a=2", "html", "", "GET", "", nil, nil, nil, nil))
+	s.Push(request.NewRequest("http://A.com:80/x?a=2&b=1#frag", "html", "", "GET", "", nil, nil, nil, nil))
+	s.Push(request.NewRequest("http://a.com/x?a=2&b=1&utm_source=feed", "html", "", "GET", "", nil, nil, nil, nil))
+	if s.Count() != 1 {
+		t.Errorf("normalized duplicates should be removed : %d", s.Count())
+	}
+
+	s = NewQueueScheduler(true)
+	s.Push(request.NewRequest("http://a.com/x?b=1&a=2", "html", "", "GET", "", nil, nil, nil, nil))
+	s.Push(request.NewRequest("http://a.com/x?a=2&b=1", "html", "", "GET", "", nil, nil, nil, nil))
+	if s.Count() != 2 {
+		t.Errorf("raw urls should be kept by default : %d", s.Count())
+	}
+
+	// the key of a request pushed before the normalizer changes is removed by Poll
+	s = NewQueueScheduler(true)
+	s.Push(request.NewRequest("http://a.com/x?b=1&a=2", "html", "", "GET", "", nil, nil, nil, nil))
+	s.SetNormalizer(dedupe.NewNormalizer())
+	s.Poll()
+	s.SetNormalizer(nil)
+	s.Push(request.NewRequest("http://a.com/x?b=1&a=2", "html", "", "GET", "", nil, nil, nil, nil))
+	if s.Count() != 1 {
+		t.Errorf("a polled url should be pushed again : %d", s.Count())
+	}
+}
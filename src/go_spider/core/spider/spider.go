@@ -1,20 +1,30 @@
 package spider
 
 import (
+	"io"
 	"math/rand"
+	"os"
+	"sync"
 	"time"
 )
 
+import (
+	"golang.org/x/net/context"
+)
+
 import (
 	"go_spider/core/common/mlog"
 	"go_spider/core/common/page"
 	"go_spider/core/common/page_items"
 	"go_spider/core/common/request"
 	"go_spider/core/common/resource_manage"
+	"go_spider/core/dedupe"
 	"go_spider/core/downloader"
 	"go_spider/core/page_processor"
 	"go_spider/core/pipeline"
+	"go_spider/core/politeness"
 	"go_spider/core/scheduler"
+	"go_spider/core/stats"
 )
 
 type Sipder struct {
@@ -23,19 +33,41 @@ type Sipder struct {
 	pDownloader      downloader.Downloader
 	pScheduler       scheduler.Scheduler
 	pPipelines       []pipeline.Pipeline
+	pPoliteness      *politeness.Politeness
+	pStats           *stats.Stats
+	pItemSchema      *page_items.Schema
+	pRetryPolicy     *downloader.RetryPolicy
+	pContentDeduper  *dedupe.ContentDeduper
+	logger           *mlog.Logger
+	hooks            hooks
 	mc               resource_manage.ResourceManage
 	threadnum        uint
 	exitWhenComplete bool
 	startSleeptime   uint
 	endSleeptime     uint
 	sleeptype        string
+
+	// crawl budget, 0 means no limit
+	maxPages  uint
+	maxDepth  int
+	timeLimit time.Duration
+
+	controlLocker *sync.Mutex
+	cancel        context.CancelFunc
+	// The resumeCh is not nil while the spider is paused and is closed by Resume.
+	resumeCh chan struct{}
+	// The closers are closed after the pipelines and the scheduler, such as the log file of a job.
+	closers []io.Closer
 }
 
+// NewSpider logs to stderr with the field task=taskname, see SetLogger. Every page is logged
+// at the debug level, so by default only the start and the end of the crawl, retries and errors are logged.
 func NewSpider(pageinst page_processor.PageProcessor, taskname string) *Sipder {
 	mlog.StraceInst().Open()
-	ap := &Sipder{taskname: taskname, pPageProcessor: pageinst}
+	ap := &Sipder{taskname: taskname, pPageProcessor: pageinst, controlLocker: new(sync.Mutex), pStats: stats.NewStats()}
+	ap.pRetryPolicy = downloader.NewRetryPolicy()
+	ap.SetLogger(mlog.NewLogger(mlog.NewTextSink(os.Stderr)))
 
-	ap.CloseFileLog()
 	ap.exitWhenComplete = true
 	ap.sleeptype = "fixed"
 	ap.startSleeptime = 0
@@ -48,8 +80,6 @@ func NewSpider(pageinst page_processor.PageProcessor, taskname string) *Sipder {
 		ap.SetDownloader(downloader.NewHttpDownloader())
 	}
 
-	mlog.StraceInst().Println("** start spider **")
-
 	ap.pPipelines = make([]pipeline.Pipeline, 0)
 
 	return ap
@@ -59,13 +89,28 @@ func (this *Sipder) Taskname() string {
 	return this.taskname
 }
 
+// SetLogger sets the logger of the spider. The field task is added to its entries,
+// so that the logs of spiders in one process are told apart.
+// Entries of level debug trace every request, info reports every crawled page with its status and duration.
+func (this *Sipder) SetLogger(logger *mlog.Logger) *Sipder {
+	this.logger = logger.With(mlog.F("task", this.taskname))
+	return this
+}
+
+func (this *Sipder) GetLogger() *mlog.Logger {
+	return this.logger
+}
+
+// CloseFileLog closes the process wide file log of mlog.LogInst used by downloaders, pipelines and schedulers.
 func (this *Sipder) CloseFileLog() *Sipder {
 	mlog.InitFilelog(false, "")
 	return this
 }
 
 func (this *Sipder) SetScheduler(s scheduler.Scheduler) *Sipder {
+	this.controlLocker.Lock()
 	this.pScheduler = s
+	this.controlLocker.Unlock()
 	return this
 }
 
@@ -74,6 +119,33 @@ func (this *Sipder) SetDownloader(d downloader.Downloader) *Sipder {
 	return this
 }
 
+// SetRetryPolicy sets which failed downloads are attempted again and the backoff between attempts.
+func (this *Sipder) SetRetryPolicy(policy *downloader.RetryPolicy) *Sipder {
+	this.pRetryPolicy = policy
+	return this
+}
+
+// SetPoliteness sets the host-aware layer limiting requests per host and honouring robots.txt.
+// Without it only the global sleep between downloads is applied.
+func (this *Sipder) SetPoliteness(p *politeness.Politeness) *Sipder {
+	this.pPoliteness = p
+	return this
+}
+
+// SetItemSchema sets the schema every record must match before it is sent to the pipelines.
+// Pages whose records do not match are logged and not output.
+func (this *Sipder) SetItemSchema(schema *page_items.Schema) *Sipder {
+	this.pItemSchema = schema
+	return this
+}
+
+// SetContentDeduper skips the pipelines of pages whose content was seen at another url before.
+// The target requests of a duplicate page are still crawled.
+func (this *Sipder) SetContentDeduper(d *dedupe.ContentDeduper) *Sipder {
+	this.pContentDeduper = d
+	return this
+}
+
 func (this *Sipder) AddUrl(url string, respType string) *Sipder {
 	req := request.NewRequest(url, respType, "", "GET", "", nil, nil, nil, nil)
 	this.AddRequest(req)
@@ -82,10 +154,10 @@ func (this *Sipder) AddUrl(url string, respType string) *Sipder {
 
 func (this *Sipder) AddRequest(req *request.Request) *Sipder {
 	if req == nil {
-		mlog.LogInst().LogError("request is nil")
+		this.logger.Error("request is nil")
 		return this
 	} else if req.GetUrl() == "" {
-		mlog.LogInst().LogError("request is empty")
+		this.logger.Error("request is empty")
 		return this
 	} else {
 		this.pScheduler.Push(req)
@@ -103,92 +175,360 @@ func (this *Sipder) SetThreadnum(i uint) *Sipder {
 	return this
 }
 
+// SetSleepTime sets the sleep before each download in millisecond.
+// The sleeptype "fixed" sleeps s, and "rand" sleeps a random time between s and e.
+func (this *Sipder) SetSleepTime(sleeptype string, s uint, e uint) *Sipder {
+	this.sleeptype = sleeptype
+	this.startSleeptime = s
+	this.endSleeptime = e
+	if this.sleeptype == "rand" && this.startSleeptime >= this.endSleeptime {
+		panic("startSleeptime must smaller than endSleeptime")
+	}
+	return this
+}
+
+// SetExitWhenComplete sets whether Run returns when the scheduler is empty and no page is in process.
+func (this *Sipder) SetExitWhenComplete(e bool) *Sipder {
+	this.exitWhenComplete = e
+	return this
+}
+
+// SetMaxPages limits how many requests are crawled in one Run. 0 means no limit.
+func (this *Sipder) SetMaxPages(n uint) *Sipder {
+	this.maxPages = n
+	return this
+}
+
+// SetMaxDepth limits how many links are followed from the seed requests. 0 means no limit.
+func (this *Sipder) SetMaxDepth(n int) *Sipder {
+	this.maxDepth = n
+	return this
+}
+
+// SetTimeLimit limits how long one Run lasts. 0 means no limit.
+func (this *Sipder) SetTimeLimit(d time.Duration) *Sipder {
+	this.timeLimit = d
+	return this
+}
+
+// Run crawls until the scheduler is drained.
 func (this *Sipder) Run() {
+	this.RunContext(context.Background())
+}
+
+// RunContext crawls until the scheduler is drained, ctx is done, Stop is called or the crawl budget is used up.
+// Requests in flight are always finished, but once ctx is done they stop waiting for the sleep,
+// the politeness of their host or their next retry, and fail instead. Pipelines are flushed and closed,
+// and a scheduler with a Close method is closed, before it returns. Requests left in the scheduler are not crawled.
+func (this *Sipder) RunContext(ctx context.Context) {
 	if this.threadnum == 0 {
 		this.threadnum = 1
 	}
 
+	if this.timeLimit > 0 {
+		var cancelTimeout context.CancelFunc
+		ctx, cancelTimeout = context.WithTimeout(ctx, this.timeLimit)
+		defer cancelTimeout()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	this.setCancel(cancel)
+	defer this.setCancel(nil)
+
+	this.logger.Info("start spider")
+	start := time.Now()
+	this.pStats.Reset()
 	this.mc = resource_manage.NewResourceManageChan(this.threadnum)
+	var wg sync.WaitGroup
+	var pages uint
+
+	for this.waitResume(ctx) {
+		if this.maxPages > 0 && pages >= this.maxPages {
+			this.logger.Info("page budget used up", mlog.F("pages", pages))
+			break
+		}
 
-	for {
 		req := this.pScheduler.Poll()
 
 		if this.mc.Has() == 0 && req == nil && this.exitWhenComplete {
-			mlog.StraceInst().Println("** end spider **")
 			break
 		} else if req == nil {
-			time.Sleep(500 * time.Millisecond)
+			select {
+			case <-ctx.Done():
+			case <-time.After(500 * time.Millisecond):
+			}
 			continue
 		}
 
 		this.mc.GetOne()
+		pages++
+		wg.Add(1)
 
-		go func(req *request.Request) {
+		go func(req *request.Request, s scheduler.Scheduler) {
+			defer wg.Done()
 			defer this.mc.FreeOne()
-			mlog.StraceInst().Println("start crawl : " + req.GetUrl())
-			this.pageProcess(req)
-		}(req)
+			if as, ok := s.(scheduler.AckScheduler); ok {
+				defer as.Ack(req)
+			}
+			this.logger.Debug("start crawl", mlog.F("url", req.GetUrl()), mlog.F("urltag", req.GetUrlTag()))
+			this.pageProcess(ctx, req)
+		}(req, this.pScheduler)
+	}
+
+	if ctx.Err() != nil {
+		this.logger.Info("stop spider", mlog.F("reason", ctx.Err()))
 	}
+	wg.Wait()
+	this.logger.Info("end spider", mlog.F("pages", pages), mlog.F("duration", time.Since(start)))
 
 	this.close()
 }
 
-// core processer
-func (this *Sipder) pageProcess(req *request.Request) {
+// Stop ends the running crawl. Run returns once the requests in flight are finished.
+func (this *Sipder) Stop() {
+	this.controlLocker.Lock()
+	if this.cancel != nil {
+		this.cancel()
+	}
+	this.controlLocker.Unlock()
+}
+
+// Pause stops dispatching new requests until Resume is called. Requests in flight are finished.
+func (this *Sipder) Pause() {
+	this.controlLocker.Lock()
+	if this.resumeCh == nil {
+		this.resumeCh = make(chan struct{})
+	}
+	this.controlLocker.Unlock()
+}
+
+// Resume continues a crawl paused by Pause.
+func (this *Sipder) Resume() {
+	this.controlLocker.Lock()
+	if this.resumeCh != nil {
+		close(this.resumeCh)
+		this.resumeCh = nil
+	}
+	this.controlLocker.Unlock()
+}
+
+func (this *Sipder) IsPaused() bool {
+	this.controlLocker.Lock()
+	defer this.controlLocker.Unlock()
+	return this.resumeCh != nil
+}
+
+func (this *Sipder) setCancel(cancel context.CancelFunc) {
+	this.controlLocker.Lock()
+	this.cancel = cancel
+	this.controlLocker.Unlock()
+}
+
+// The waitResume blocks while the spider is paused and returns false when ctx is done.
+func (this *Sipder) waitResume(ctx context.Context) bool {
+	this.controlLocker.Lock()
+	resumeCh := this.resumeCh
+	this.controlLocker.Unlock()
+
+	if resumeCh != nil {
+		this.logger.Info("pause spider")
+		select {
+		case <-resumeCh:
+			this.logger.Info("resume spider")
+		case <-ctx.Done():
+		}
+	}
+	return ctx.Err() == nil
+}
+
+// core processer, the waits between download attempts end when ctx is done
+func (this *Sipder) pageProcess(ctx context.Context, req *request.Request) {
 	var p *page.Page
+	downloaded := false
 	defer func() {
 		if err := recover(); err != nil {
+			errormsg := "pageProcess error"
 			if strerr, ok := err.(string); ok {
-				mlog.LogInst().LogError(strerr)
+				errormsg = strerr
+			}
+			this.logger.Error("page process panic", mlog.F("url", req.GetUrl()), mlog.F("urltag", req.GetUrlTag()), mlog.F("error", errormsg))
+			if downloaded {
+				this.fireProcessError(req, errormsg)
 			} else {
-				mlog.LogInst().LogError("pageProcess error")
+				this.fireError(req, 0, errormsg)
 			}
 		}
 	}()
 
+	if this.pPoliteness != nil && !this.pPoliteness.Allowed(req) {
+		return
+	}
+
 	// download page
-	for i := 0; i < 3; i++ {
-		this.sleep()
-		p = this.pDownloader.Download(req)
-		if p.IsSucc() {
+	var duration time.Duration
+	for attempt := 1; ; attempt++ {
+		if !sleepContext(ctx, this.sleepTime()) {
+			p = page.NewPage(req)
+			p.SetStatus(true, ctx.Err().Error())
+			break
+		}
+		this.fireRequest(req)
+		start := time.Now()
+		p = this.download(ctx, req)
+		duration = time.Since(start)
+		if p.IsSucc() || ctx.Err() != nil {
+			break
+		}
+		delay, retry := this.pRetryPolicy.Backoff(p, attempt)
+		if !retry {
+			break
+		}
+		this.logger.Warn("retry crawl", mlog.F("url", req.GetUrl()), mlog.F("status", p.GetStatusCode()),
+			mlog.F("attempt", attempt), mlog.F("delay", delay), mlog.F("error", p.Errormsg()))
+		this.pStats.AddRetry(hostOf(req), p.GetStatusCode())
+		if !sleepContext(ctx, delay) {
 			break
 		}
 	}
 
 	if !p.IsSucc() {
+		this.logger.Error("crawl failed", mlog.F("url", req.GetUrl()), mlog.F("urltag", req.GetUrlTag()),
+			mlog.F("status", p.GetStatusCode()), mlog.F("duration", duration), mlog.F("error", p.Errormsg()))
+		this.fireError(req, p.GetStatusCode(), p.Errormsg())
 		return
 	}
+	if p.GetSkip() {
+		// skipped by the downloader, such as an unchanged page
+		this.logger.Debug("crawl skipped", mlog.F("url", req.GetUrl()), mlog.F("urltag", req.GetUrlTag()),
+			mlog.F("status", p.GetStatusCode()), mlog.F("duration", duration))
+		this.pStats.AddSkip(hostOf(req), p.GetStatusCode())
+		return
+	}
+	downloaded = true
+	this.logger.Debug("crawled", mlog.F("url", req.GetUrl()), mlog.F("urltag", req.GetUrlTag()),
+		mlog.F("status", p.GetStatusCode()), mlog.F("duration", duration))
+	this.fireResponse(p)
 
 	this.pPageProcessor.Process(p)
-	for _, req := range p.GetTargetRequests() {
-		this.AddRequest(req)
+	for _, treq := range p.GetTargetRequests() {
+		treq.SetDepth(req.GetDepth() + 1)
+		if this.maxDepth > 0 && treq.GetDepth() > this.maxDepth {
+			continue
+		}
+		this.AddRequest(treq)
 	}
 
 	// output
 	if !p.GetSkip() {
-		for _, pip := range this.pPipelines {
-			pip.Process(p.GetPageItems(), this)
+		if this.pContentDeduper != nil {
+			if first, dup := this.pContentDeduper.Seen(req.GetUrl(), pageText(p)); dup {
+				this.logger.Debug("duplicate content", mlog.F("url", req.GetUrl()), mlog.F("first", first))
+				return
+			}
 		}
+		if this.pItemSchema != nil {
+			if err := p.GetPageItems().Validate(this.pItemSchema); err != nil {
+				this.logger.Error("item schema error", mlog.F("url", req.GetUrl()), mlog.F("error", err))
+				return
+			}
+		}
+		this.fireItem(p.GetPageItems())
+		this.processPipelines(p.GetPageItems())
 	}
+}
+
+// The download returns a failed page without downloading when ctx is done
+// while it waits for the politeness of the host.
+func (this *Sipder) download(ctx context.Context, req *request.Request) *page.Page {
+	if this.pPoliteness != nil {
+		if err := this.pPoliteness.Acquire(ctx, req); err != nil {
+			p := page.NewPage(req)
+			p.SetStatus(true, err.Error())
+			return p
+		}
+		defer this.pPoliteness.Release(req)
+	}
+	return this.pDownloader.Download(req)
+}
 
+// The pageText is the content of p compared by the content deduper, the body text of html pages.
+func pageText(p *page.Page) string {
+	if doc := p.GetHtmlParser(); doc != nil {
+		return doc.Find("body").Text()
+	}
+	return p.GetBodyStr()
 }
 
-func (this *Sipder) sleep() {
+// The sleepTime is the sleep before each download set by SetSleepTime.
+func (this *Sipder) sleepTime() time.Duration {
 	if this.sleeptype == "fixed" {
-		time.Sleep(time.Duration(this.startSleeptime) * time.Millisecond)
+		return time.Duration(this.startSleeptime) * time.Millisecond
 	} else if this.sleeptype == "rand" {
 		sleeptime := rand.Intn(int(this.endSleeptime-this.startSleeptime)) + int(this.startSleeptime)
-		time.Sleep(time.Duration(sleeptime) * time.Millisecond)
+		return time.Duration(sleeptime) * time.Millisecond
+	}
+	return 0
+}
+
+// The sleepContext sleeps d and returns true, or returns false as soon as ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
 	}
 }
 
 func (this *Sipder) close() {
+	this.closePipelines()
+	this.closeScheduler()
+	this.closeResources()
 	this.SetScheduler(scheduler.NewQueueScheduler(false))
 	this.SetDownloader(downloader.NewHttpDownloader())
 	this.pPipelines = make([]pipeline.Pipeline, 0)
 	this.exitWhenComplete = true
 }
 
+// The closePipelines flushes and closes the pipelines that hold buffered items or resources.
+func (this *Sipder) closePipelines() {
+	for _, pip := range this.pPipelines {
+		if fp, ok := pip.(pipeline.FlushPipeline); ok {
+			if err := fp.Flush(); err != nil {
+				this.logger.Error("pipeline flush error", mlog.F("error", err))
+			}
+		}
+		if cp, ok := pip.(pipeline.ClosePipeline); ok {
+			if err := cp.Close(); err != nil {
+				this.logger.Error("pipeline close error", mlog.F("error", err))
+			}
+		}
+	}
+}
+
+// The closeScheduler closes a scheduler holding resources, such as the log file of a FileScheduler.
+func (this *Sipder) closeScheduler() {
+	if cs, ok := this.pScheduler.(io.Closer); ok {
+		if err := cs.Close(); err != nil {
+			this.logger.Error("scheduler close error", mlog.F("error", err))
+		}
+	}
+}
+
+// The closeResources closes the closers opened with the spider.
+func (this *Sipder) closeResources() {
+	for _, c := range this.closers {
+		if err := c.Close(); err != nil {
+			this.logger.Error("close error", mlog.F("error", err))
+		}
+	}
+}
+
 // Deal with one url and return the PageItems with other setting.
 func (this *Sipder) GetByRequest(req *request.Request) *page_items.PageItems {
 	var reqs []*request.Request
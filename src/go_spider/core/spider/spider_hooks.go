@@ -0,0 +1,118 @@
+package spider
+
+import (
+	"net/url"
+	"time"
+)
+
+import (
+	"go_spider/core/common/page"
+	"go_spider/core/common/page_items"
+	"go_spider/core/common/request"
+	"go_spider/core/stats"
+)
+
+// The OnRequestFunc is called before every download attempt of a request.
+type OnRequestFunc func(req *request.Request)
+
+// The OnResponseFunc is called with every page downloaded successfully.
+type OnResponseFunc func(p *page.Page)
+
+// The OnErrorFunc is called when a request fails after all retries or its processing panics.
+type OnErrorFunc func(req *request.Request, errormsg string)
+
+// The OnItemFunc is called with every PageItems before it is sent to the pipelines.
+type OnItemFunc func(items *page_items.PageItems)
+
+// The hooks are called from the crawl goroutines, so they must be safe for concurrent use.
+type hooks struct {
+	onRequest  []OnRequestFunc
+	onResponse []OnResponseFunc
+	onError    []OnErrorFunc
+	onItem     []OnItemFunc
+}
+
+// OnRequest adds a hook called before every download attempt.
+func (this *Sipder) OnRequest(f OnRequestFunc) *Sipder {
+	this.hooks.onRequest = append(this.hooks.onRequest, f)
+	return this
+}
+
+// OnResponse adds a hook called with every page downloaded successfully.
+func (this *Sipder) OnResponse(f OnResponseFunc) *Sipder {
+	this.hooks.onResponse = append(this.hooks.onResponse, f)
+	return this
+}
+
+// OnError adds a hook called when a request fails after all retries or its processing panics.
+func (this *Sipder) OnError(f OnErrorFunc) *Sipder {
+	this.hooks.onError = append(this.hooks.onError, f)
+	return this
+}
+
+// OnItem adds a hook called with every PageItems before it is sent to the pipelines.
+func (this *Sipder) OnItem(f OnItemFunc) *Sipder {
+	this.hooks.onItem = append(this.hooks.onItem, f)
+	return this
+}
+
+// GetStats returns the statistics of the current or last crawl.
+func (this *Sipder) GetStats() stats.Snapshot {
+	this.controlLocker.Lock()
+	s := this.pScheduler
+	this.controlLocker.Unlock()
+	return this.pStats.Snapshot(s.Count())
+}
+
+func (this *Sipder) fireRequest(req *request.Request) {
+	for _, f := range this.hooks.onRequest {
+		f(req)
+	}
+}
+
+func (this *Sipder) fireResponse(p *page.Page) {
+	this.pStats.AddDownload(hostOf(p.GetRequest()), p.GetStatusCode(), len(p.GetBodyStr()))
+	for _, f := range this.hooks.onResponse {
+		f(p)
+	}
+}
+
+func (this *Sipder) fireError(req *request.Request, statusCode int, errormsg string) {
+	this.pStats.AddFailure(hostOf(req), statusCode)
+	for _, f := range this.hooks.onError {
+		f(req, errormsg)
+	}
+}
+
+// The fireProcessError reports a downloaded page whose processing panicked,
+// its host is counted as a process failure and not as a failed download.
+func (this *Sipder) fireProcessError(req *request.Request, errormsg string) {
+	this.pStats.AddProcessFailure(hostOf(req))
+	for _, f := range this.hooks.onError {
+		f(req, errormsg)
+	}
+}
+
+func (this *Sipder) fireItem(items *page_items.PageItems) {
+	this.pStats.AddItems()
+	for _, f := range this.hooks.onItem {
+		f(items)
+	}
+}
+
+// The processPipelines sends items to every pipeline and records the time it takes.
+func (this *Sipder) processPipelines(items *page_items.PageItems) {
+	for _, pip := range this.pPipelines {
+		start := time.Now()
+		pip.Process(items, this)
+		this.pStats.AddPipeline(time.Since(start))
+	}
+}
+
+func hostOf(req *request.Request) string {
+	u, err := url.Parse(req.GetUrl())
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,146 @@
+package spider
+
+import (
+	"sync"
+	"testing"
+)
+
+import (
+	"go_spider/core/common/page"
+	"go_spider/core/common/page_items"
+	"go_spider/core/common/request"
+	"go_spider/core/downloader"
+)
+
+func TestSpiderHooks(t *testing.T) {
+	var locker sync.Mutex
+	attempts := make(map[string]int)
+	fetch := downloaderFunc(func(req *request.Request) *page.Page {
+		locker.Lock()
+		defer locker.Unlock()
+		url := req.GetUrl()
+		attempts[url]++
+
+		p := page.NewPage(req)
+		switch {
+		case url == "http://b.com/retry" && attempts[url] == 1:
+			p.SetStatusCode(503).SetStatus(true, "503 Service Unavailable")
+		case url == "http://c.com/fail":
+			p.SetStatusCode(404).SetStatus(true, "404 Not Found")
+		default:
+			p.SetStatusCode(200).SetBodyStr("<html></html>")
+		}
+		return p
+	})
+
+	s, _ := newTestSpider(func(p *page.Page) {
+		if p.GetRequest().GetUrl() == "http://a.com/panic" {
+			panic("boom")
+		}
+		p.AddField("url", p.GetRequest().GetUrl())
+	}, "http://a.com/ok", "http://a.com/panic", "http://b.com/retry", "http://c.com/fail")
+	s.SetDownloader(fetch).SetThreadnum(2)
+	s.SetRetryPolicy(&downloader.RetryPolicy{MaxAttempts: 2, RetryStatus: map[int]bool{503: true}})
+
+	requests := make(map[string]int)
+	responses := make(map[string]int)
+	errors := make(map[string]string)
+	items := make(map[string]string)
+	s.OnRequest(func(req *request.Request) {
+		locker.Lock()
+		requests[req.GetUrl()]++
+		locker.Unlock()
+	}).OnResponse(func(p *page.Page) {
+		locker.Lock()
+		if p.GetStatusCode() != 200 {
+			t.Errorf("response hook called with status %d", p.GetStatusCode())
+		}
+		responses[p.GetRequest().GetUrl()]++
+		locker.Unlock()
+	}).OnError(func(req *request.Request, errormsg string) {
+		locker.Lock()
+		if _, ok := errors[req.GetUrl()]; ok {
+			t.Errorf("error hook called twice for %s", req.GetUrl())
+		}
+		errors[req.GetUrl()] = errormsg
+		locker.Unlock()
+	}).OnItem(func(pi *page_items.PageItems) {
+		locker.Lock()
+		url, _ := pi.GetItem("url")
+		items[pi.GetRequest().GetUrl()] = url
+		locker.Unlock()
+	})
+	s.Run()
+
+	wantRequests := map[string]int{"http://a.com/ok": 1, "http://a.com/panic": 1, "http://b.com/retry": 2, "http://c.com/fail": 1}
+	for url, n := range wantRequests {
+		if requests[url] != n {
+			t.Errorf("request hook called %d times for %s, want %d", requests[url], url, n)
+		}
+	}
+	if len(responses) != 3 || responses["http://a.com/ok"] != 1 || responses["http://a.com/panic"] != 1 || responses["http://b.com/retry"] != 1 {
+		t.Errorf("response hook error : %v", responses)
+	}
+	if len(errors) != 2 || errors["http://c.com/fail"] != "404 Not Found" || errors["http://a.com/panic"] != "boom" {
+		t.Errorf("error hook error : %v", errors)
+	}
+	if len(items) != 2 || items["http://a.com/ok"] != "http://a.com/ok" || items["http://b.com/retry"] != "http://b.com/retry" {
+		t.Errorf("item hook error : %v", items)
+	}
+
+	snapshot := s.GetStats()
+	if h := snapshot.Hosts["a.com"]; h.Downloaded != 2 || h.Failed != 0 || h.ProcessFailed != 1 || h.Retried != 0 {
+		t.Errorf("a.com stats error : %+v", h)
+	}
+	if h := snapshot.Hosts["b.com"]; h.Downloaded != 1 || h.Failed != 0 || h.Retried != 1 {
+		t.Errorf("b.com stats error : %+v", h)
+	}
+	if h := snapshot.Hosts["c.com"]; h.Downloaded != 0 || h.Failed != 1 || h.Retried != 0 {
+		t.Errorf("c.com stats error : %+v", h)
+	}
+	if snapshot.Items != 2 || snapshot.StatusCodes[503] != 1 || snapshot.StatusCodes[404] != 1 {
+		t.Errorf("stats error : %+v", snapshot)
+	}
+}
+
+func TestSpiderGetStatsDuringRun(t *testing.T) {
+	s, _ := newTestSpider(func(p *page.Page) {}, "http://a.com/1", "http://a.com/2")
+	s.SetDownloader(&countDownloader{locker: new(sync.Mutex)})
+
+	done := make(chan struct{})
+	go func() {
+		s.Run()
+		close(done)
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			s.GetStats()
+		}
+	}
+	if snapshot := s.GetStats(); snapshot.Downloaded != 2 {
+		t.Errorf("stats after run error : %+v", snapshot)
+	}
+}
+
+func TestSpiderSkippedPage(t *testing.T) {
+	s, _ := newTestSpider(func(p *page.Page) {
+		t.Error("a page skipped by the downloader should not be processed")
+	}, "http://a.com")
+	s.SetDownloader(downloaderFunc(func(req *request.Request) *page.Page {
+		// an unchanged page answered with 304
+		p := page.NewPage(req).SetStatusCode(304)
+		p.SetSkip(true)
+		return p
+	}))
+	s.OnError(func(req *request.Request, errormsg string) {
+		t.Errorf("a skipped page should not be an error : %s", errormsg)
+	})
+	s.Run()
+
+	if h := s.GetStats().Hosts["a.com"]; h.Skipped != 1 || h.Downloaded != 0 || h.Failed != 0 {
+		t.Errorf("a.com stats error : %+v", h)
+	}
+}
@@ -0,0 +1,409 @@
+package spider
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+import (
+	"github.com/garyburd/redigo/redis"
+)
+
+import (
+	"go_spider/core/common/config"
+	"go_spider/core/common/mlog"
+	"go_spider/core/common/page_items"
+	"go_spider/core/common/request"
+	"go_spider/core/downloader"
+	"go_spider/core/page_processor"
+	"go_spider/core/pipeline"
+	"go_spider/core/scheduler"
+)
+
+// LoadJob builds a Sipder from the job config file path.
+// Relative paths in the file are resolved in the directory of the file.
+func LoadJob(path string) (*Sipder, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	conf := config.NewConfig()
+	if err = conf.LoadString(string(b)); err != nil {
+		return nil, err
+	}
+	return NewJob(conf, filepath.Dir(path))
+}
+
+// NewJob builds a Sipder from the job config conf, resolving relative paths in dir.
+// Pages are extracted by a RuleProcessor and the seeds are added to the scheduler.
+//
+// Comments take whole lines, as values keep everything after "=". Durations are in seconds
+// and sleep times in milliseconds. Example:
+//
+//	name = github
+//	# RuleSet file in json, or in yaml by a .yaml or .yml extension, required
+//	rules = rules.json
+//	# Schema file checking the items
+//	schema = schema.json
+//	threads = 3
+//	# "fixed <ms>" or "rand <min ms> <max ms>"
+//	sleep = rand 100 500
+//	exit_when_complete = true
+//	max_pages = 1000
+//	max_depth = 3
+//	time_limit = 3600
+//	# debug logs every page, info, warn or error; text or json; a directory holding daily files, stderr when not set
+//	log_level = info
+//	log_format = text
+//	log_dir = log
+//
+//	[seeds]
+//	urls = https://github.com/hu17889, https://github.com/golang
+//	# one url per line
+//	file = seeds.txt
+//	type = html
+//	urltag = user
+//
+//	[scheduler]
+//	# queue, simple, file with dir, or redis with address, key and lease_timeout
+//	type = file
+//	remove_duplicate = true
+//	dir = state
+//
+//	[downloader]
+//	# http, or file reading pages under root
+//	type = http
+//	timeout = 30
+//	connect_timeout = 10
+//	response_header_timeout = 30
+//	max_body_size = 10485760
+//	conditional = true
+//	proxies = http://127.0.0.1:8080, socks5://127.0.0.1:1080
+//	retries = 3
+//	# records and replays responses, cache_mode is record, replay or record_missing
+//	cache_dir = cache
+//	cache_mode = record_missing
+//
+//	[headers]
+//	User-Agent = go_spider
+//
+//	[pipeline.out]
+//	# console, file, jsonl or csv with columns, the section suffix by default
+//	type = jsonl
+//	path = items.jsonl
+//	url_field = url
+func NewJob(conf *config.Config, dir string) (s *Sipder, err error) {
+	// The job keeps the spider after an error, whose pipelines, scheduler and log file opened
+	// before the error are closed.
+	var job *Sipder
+	defer func() {
+		// pipeline constructors panic when their file can not be opened
+		if r := recover(); r != nil {
+			msg, ok := r.(string)
+			if !ok {
+				panic(r)
+			}
+			s, err = nil, errors.New(msg)
+		}
+		if err != nil && job != nil {
+			job.closePipelines()
+			job.closeScheduler()
+			job.closeResources()
+		}
+	}()
+
+	if conf.GlobalGet("rules") == "" {
+		return nil, errors.New("job rules is not set")
+	}
+	rules, err := page_processor.LoadRuleSet(jobPath(dir, conf.GlobalGet("rules")))
+	if err != nil {
+		return nil, errors.New("job rules error : " + err.Error())
+	}
+
+	name := conf.GlobalGet("name")
+	if name == "" {
+		name = "job"
+	}
+	s = NewSpider(page_processor.NewRuleProcessor(rules), name)
+	job = s
+
+	if conf.GlobalHas("schema") {
+		b, err := ioutil.ReadFile(jobPath(dir, conf.GlobalGet("schema")))
+		if err != nil {
+			return nil, errors.New("job schema error : " + err.Error())
+		}
+		schema := new(page_items.Schema)
+		if err = json.Unmarshal(b, schema); err != nil {
+			return nil, errors.New("job schema error : " + err.Error())
+		}
+		s.SetItemSchema(schema)
+	}
+
+	if conf.GlobalHas("threads") {
+		s.SetThreadnum(uint(conf.GlobalGetInt("threads")))
+	}
+	if conf.GlobalHas("sleep") {
+		if err = setJobSleep(s, conf.GlobalGetSlice("sleep", " ")); err != nil {
+			return nil, err
+		}
+	}
+	if conf.GlobalHas("exit_when_complete") {
+		s.SetExitWhenComplete(conf.GlobalGetBool("exit_when_complete"))
+	}
+	s.SetMaxPages(uint(conf.GlobalGetInt("max_pages")))
+	s.SetMaxDepth(conf.GlobalGetInt("max_depth"))
+	s.SetTimeLimit(conf.GlobalGetDuration("time_limit"))
+	if err = setJobLogger(s, conf, dir, name); err != nil {
+		return nil, err
+	}
+
+	if err = setJobScheduler(s, conf, dir, name); err != nil {
+		return nil, err
+	}
+	if err = setJobDownloader(s, conf, dir); err != nil {
+		return nil, err
+	}
+	if err = addJobPipelines(s, conf, dir); err != nil {
+		return nil, err
+	}
+	if err = addJobSeeds(s, conf, dir); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func setJobSleep(s *Sipder, parts []string) error {
+	if len(parts) == 0 {
+		return errors.New("job sleep should be \"fixed <ms>\" or \"rand <min ms> <max ms>\"")
+	}
+
+	var times []uint
+	for _, part := range parts[1:] {
+		if part == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return errors.New("job sleep error : " + err.Error())
+		}
+		times = append(times, uint(n))
+	}
+
+	switch {
+	case parts[0] == "fixed" && len(times) == 1:
+		s.SetSleepTime("fixed", times[0], 0)
+	case parts[0] == "rand" && len(times) == 2 && times[0] < times[1]:
+		s.SetSleepTime("rand", times[0], times[1])
+	default:
+		return errors.New("job sleep should be \"fixed <ms>\" or \"rand <min ms> <max ms>\"")
+	}
+	return nil
+}
+
+func setJobLogger(s *Sipder, conf *config.Config, dir string, name string) error {
+	var w io.Writer = os.Stderr
+	if conf.GlobalHas("log_dir") {
+		rf := mlog.NewRotateFile(jobPath(dir, conf.GlobalGet("log_dir")), name)
+		s.closers = append(s.closers, rf)
+		w = rf
+	}
+
+	var sink mlog.Sink
+	switch conf.GlobalGet("log_format") {
+	case "", "text":
+		sink = mlog.NewTextSink(w)
+	case "json":
+		sink = mlog.NewJsonSink(w)
+	default:
+		return errors.New("job log_format " + conf.GlobalGet("log_format") + " is unknown")
+	}
+
+	logger := mlog.NewLogger(sink)
+	if conf.GlobalHas("log_level") {
+		level, err := mlog.ParseLevel(conf.GlobalGet("log_level"))
+		if err != nil {
+			return errors.New("job " + err.Error())
+		}
+		logger.SetLevel(level)
+	}
+	s.SetLogger(logger)
+	return nil
+}
+
+func setJobScheduler(s *Sipder, conf *config.Config, dir string, name string) error {
+	rm := conf.SectionGetBool("scheduler", "remove_duplicate")
+	switch conf.SectionGet("scheduler", "type") {
+	case "", "queue":
+		s.SetScheduler(scheduler.NewQueueScheduler(rm))
+	case "simple":
+		s.SetScheduler(scheduler.NewSimpleScheduler())
+	case "file":
+		if !conf.SectionHas("scheduler", "dir") {
+			return errors.New("job scheduler dir is not set")
+		}
+		s.SetScheduler(scheduler.NewFileScheduler(jobPath(dir, conf.SectionGet("scheduler", "dir")), rm))
+	case "redis":
+		address := conf.SectionGet("scheduler", "address")
+		if address == "" {
+			address = "127.0.0.1:6379"
+		}
+		key := conf.SectionGet("scheduler", "key")
+		if key == "" {
+			key = name
+		}
+		pool := redis.NewPool(func() (redis.Conn, error) { return redis.Dial("tcp", address) }, 4)
+		rs := scheduler.NewRedisScheduler(pool, key, rm)
+		if conf.SectionHas("scheduler", "lease_timeout") {
+			rs.SetLeaseTimeout(conf.SectionGetDuration("scheduler", "lease_timeout"))
+		}
+		s.SetScheduler(rs)
+	default:
+		return errors.New("job scheduler type " + conf.SectionGet("scheduler", "type") + " is unknown")
+	}
+	return nil
+}
+
+func setJobDownloader(s *Sipder, conf *config.Config, dir string) error {
+	var d downloader.Downloader
+	switch conf.SectionGet("downloader", "type") {
+	case "", "http":
+		hd := downloader.NewHttpDownloader()
+		if conf.SectionHas("downloader", "timeout") {
+			hd.SetTimeout(conf.SectionGetDuration("downloader", "timeout"))
+		}
+		if conf.SectionHas("downloader", "connect_timeout") {
+			hd.SetConnectTimeout(conf.SectionGetDuration("downloader", "connect_timeout"))
+		}
+		if conf.SectionHas("downloader", "response_header_timeout") {
+			hd.SetResponseHeaderTimeout(conf.SectionGetDuration("downloader", "response_header_timeout"))
+		}
+		if conf.SectionHas("downloader", "max_body_size") {
+			size, err := strconv.ParseInt(conf.SectionGet("downloader", "max_body_size"), 10, 64)
+			if err != nil {
+				return errors.New("job downloader max_body_size error : " + err.Error())
+			}
+			hd.SetMaxBodySize(size)
+		}
+		hd.SetConditional(conf.SectionGetBool("downloader", "conditional"))
+
+		if proxies := conf.SectionGetSlice("downloader", "proxies", ","); len(proxies) > 0 {
+			pool := downloader.NewProxyPool()
+			for _, proxy := range proxies {
+				if err := pool.AddProxy(proxy, 1); err != nil {
+					return errors.New("job downloader proxies error : " + err.Error())
+				}
+			}
+			hd.SetProxyProvider(pool)
+		}
+
+		if headers := conf.SectionContent("headers"); len(headers) > 0 {
+			header := make(http.Header)
+			for key, value := range headers {
+				header.Set(key, value)
+			}
+			hd.SetHeader(header)
+		}
+		d = hd
+	case "file":
+		d = downloader.NewFileDownloader(jobPath(dir, conf.SectionGet("downloader", "root")))
+	default:
+		return errors.New("job downloader type " + conf.SectionGet("downloader", "type") + " is unknown")
+	}
+
+	if conf.SectionHas("downloader", "cache_dir") {
+		cd := downloader.NewCacheDownloader(jobPath(dir, conf.SectionGet("downloader", "cache_dir")), d)
+		switch conf.SectionGet("downloader", "cache_mode") {
+		case "", "record_missing":
+		case "record":
+			cd.SetMode(downloader.CacheRecord)
+		case "replay":
+			cd.SetMode(downloader.CacheReplay)
+		default:
+			return errors.New("job downloader cache_mode " + conf.SectionGet("downloader", "cache_mode") + " is unknown")
+		}
+		d = cd
+	}
+	s.SetDownloader(d)
+
+	if conf.SectionHas("downloader", "retries") {
+		policy := downloader.NewRetryPolicy()
+		policy.MaxAttempts = conf.SectionGetInt("downloader", "retries") + 1
+		s.SetRetryPolicy(policy)
+	}
+	return nil
+}
+
+// The addJobPipelines adds the pipelines of the "pipeline.<name>" sections in the order of the file.
+func addJobPipelines(s *Sipder, conf *config.Config, dir string) error {
+	for _, section := range conf.Sections() {
+		if !strings.HasPrefix(section, "pipeline.") {
+			continue
+		}
+		ptype := conf.SectionGet(section, "type")
+		if ptype == "" {
+			ptype = strings.TrimPrefix(section, "pipeline.")
+		}
+		path := jobPath(dir, conf.SectionGet(section, "path"))
+		if ptype != "console" && conf.SectionGet(section, "path") == "" {
+			return errors.New("job " + section + " path is not set")
+		}
+		urlField := conf.SectionGet(section, "url_field")
+
+		switch ptype {
+		case "console":
+			s.AddPipeline(pipeline.NewPipelineConsole())
+		case "file":
+			s.AddPipeline(pipeline.NewPipelineFile(path))
+		case "jsonl":
+			s.AddPipeline(pipeline.NewPipelineJsonLines(path).SetUrlField(urlField))
+		case "csv":
+			columns := conf.SectionGetSlice(section, "columns", ",")
+			if len(columns) == 0 {
+				return errors.New("job " + section + " columns is not set")
+			}
+			s.AddPipeline(pipeline.NewPipelineCsv(path, columns).SetUrlField(urlField))
+		default:
+			return errors.New("job " + section + " type " + ptype + " is unknown")
+		}
+	}
+	return nil
+}
+
+func addJobSeeds(s *Sipder, conf *config.Config, dir string) error {
+	urls := conf.SectionGetSlice("seeds", "urls", ",")
+	if conf.SectionHas("seeds", "file") {
+		b, err := ioutil.ReadFile(jobPath(dir, conf.SectionGet("seeds", "file")))
+		if err != nil {
+			return errors.New("job seeds error : " + err.Error())
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			if line = strings.TrimSpace(line); line != "" && line[0] != '#' {
+				urls = append(urls, line)
+			}
+		}
+	}
+
+	respType := conf.SectionGet("seeds", "type")
+	if respType == "" {
+		respType = "html"
+	}
+	urltag := conf.SectionGet("seeds", "urltag")
+	for _, url := range urls {
+		s.AddRequest(request.NewRequest(url, respType, urltag, "GET", "", nil, nil, nil, nil))
+	}
+	return nil
+}
+
+func jobPath(dir string, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
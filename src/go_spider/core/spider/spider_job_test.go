@@ -0,0 +1,114 @@
+package spider
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+import (
+	"go_spider/core/common/config"
+)
+
+func TestLoadJob(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Job") != "test" {
+			http.Error(w, "no header", http.StatusForbidden)
+			return
+		}
+		if r.URL.Path == "/" {
+			w.Write([]byte(`<html><body><h1>home</h1><a href="/a">a</a></body></html>`))
+			return
+		}
+		w.Write([]byte(`<html><body><h1>a</h1></body></html>`))
+	}))
+	defer ts.Close()
+
+	dir, _ := ioutil.TempDir("", "spider_job")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "rules.json"), []byte(`{"rules": [{
+		"fields": [{"name": "title", "css": "h1", "required": true}],
+		"links": [{"css": "a"}]
+	}]}`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "job.conf"), []byte(`
+name = test
+rules = rules.json
+threads = 2
+sleep = fixed 1
+
+[seeds]
+urls = `+ts.URL+`/
+
+[scheduler]
+remove_duplicate = true
+
+[headers]
+X-Job = test
+
+[pipeline.out]
+type = jsonl
+path = items.jsonl
+`), 0644)
+
+	s, err := LoadJob(filepath.Join(dir, "job.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Run()
+
+	b, _ := ioutil.ReadFile(filepath.Join(dir, "items.jsonl"))
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 2 || !strings.Contains(string(b), `"title":"home"`) || !strings.Contains(string(b), `"title":"a"`) {
+		t.Error("job output error : " + string(b))
+	}
+}
+
+func TestNewJobError(t *testing.T) {
+	conf := config.NewConfig()
+	if _, err := NewJob(conf, ""); err == nil {
+		t.Error("job without rules should fail")
+	}
+
+	dir, _ := ioutil.TempDir("", "spider_job")
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "rules.json"), []byte(`{"rules": []}`), 0644)
+	conf.GlobalSet("rules", "rules.json")
+	conf.SectionSet("scheduler", "type", "unknown")
+	if _, err := NewJob(conf, dir); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Errorf("unknown scheduler should fail : %v", err)
+	}
+
+	conf.SectionSet("scheduler", "type", "queue")
+	conf.SectionSet("pipeline.jsonl", "path", filepath.Join(dir, "missing", "items.jsonl"))
+	if _, err := NewJob(conf, dir); err == nil || !strings.Contains(err.Error(), "open failed") {
+		t.Errorf("pipeline open error should be returned : %v", err)
+	}
+
+	// the files opened before the error are closed
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err == nil {
+		conf.GlobalSet("log_dir", "log")
+		conf.SectionSet("scheduler", "type", "file")
+		conf.SectionSet("scheduler", "dir", "frontier")
+		conf.SectionSet("pipeline.jsonl", "path", "items.jsonl")
+		conf.SectionSet("pipeline.bad", "type", "unknown")
+		conf.SectionSet("pipeline.bad", "path", "bad")
+		if _, err := NewJob(conf, dir); err == nil || !strings.Contains(err.Error(), "unknown") {
+			t.Errorf("unknown pipeline should fail : %v", err)
+		}
+		if after, _ := ioutil.ReadDir("/proc/self/fd"); len(after) > len(fds) {
+			t.Errorf("%d files left open by a failed job", len(after)-len(fds))
+		}
+	}
+
+	conf = config.NewConfig()
+	conf.GlobalSet("rules", "rules.json")
+	conf.GlobalSet("sleep", "")
+	if _, err := NewJob(conf, dir); err == nil || !strings.Contains(err.Error(), "sleep") {
+		t.Errorf("empty sleep should fail : %v", err)
+	}
+}
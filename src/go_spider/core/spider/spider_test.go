@@ -0,0 +1,330 @@
+package spider
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"golang.org/x/net/context"
+)
+
+import (
+	"go_spider/core/common/com_interfaces"
+	"go_spider/core/common/mlog"
+	"go_spider/core/common/page"
+	"go_spider/core/common/page_items"
+	"go_spider/core/common/request"
+	"go_spider/core/downloader"
+)
+
+type downloaderFunc func(req *request.Request) *page.Page
+
+func (f downloaderFunc) Download(req *request.Request) *page.Page {
+	return f(req)
+}
+
+type processorFunc func(p *page.Page)
+
+func (f processorFunc) Process(p *page.Page) {
+	f(p)
+}
+
+// The fakeScheduler is a FIFO Scheduler counting the polled requests and recording its Close.
+type fakeScheduler struct {
+	locker *sync.Mutex
+	queue  []*request.Request
+	polled int
+	closed bool
+}
+
+func newFakeScheduler() *fakeScheduler {
+	return &fakeScheduler{locker: new(sync.Mutex)}
+}
+
+func (this *fakeScheduler) Push(req *request.Request) {
+	this.locker.Lock()
+	this.queue = append(this.queue, req)
+	this.locker.Unlock()
+}
+
+func (this *fakeScheduler) Poll() *request.Request {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	if len(this.queue) == 0 {
+		return nil
+	}
+	req := this.queue[0]
+	this.queue = this.queue[1:]
+	this.polled++
+	return req
+}
+
+func (this *fakeScheduler) Count() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return len(this.queue)
+}
+
+func (this *fakeScheduler) Close() error {
+	this.locker.Lock()
+	this.closed = true
+	this.locker.Unlock()
+	return nil
+}
+
+func (this *fakeScheduler) Polled() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return this.polled
+}
+
+// The recordPipeline records the calls of the spider in order.
+type recordPipeline struct {
+	locker *sync.Mutex
+	calls  []string
+}
+
+func (this *recordPipeline) Process(items *page_items.PageItems, t com_interfaces.Task) {
+	this.record("process")
+}
+
+func (this *recordPipeline) Flush() error {
+	this.record("flush")
+	return nil
+}
+
+func (this *recordPipeline) Close() error {
+	this.record("close")
+	return nil
+}
+
+func (this *recordPipeline) record(call string) {
+	this.locker.Lock()
+	this.calls = append(this.calls, call)
+	this.locker.Unlock()
+}
+
+// The countDownloader downloads every request successfully and counts them.
+type countDownloader struct {
+	locker *sync.Mutex
+	urls   []string
+}
+
+func (this *countDownloader) Download(req *request.Request) *page.Page {
+	this.locker.Lock()
+	this.urls = append(this.urls, req.GetUrl())
+	this.locker.Unlock()
+	return page.NewPage(req).SetStatusCode(200).SetBodyStr("<html></html>")
+}
+
+func (this *countDownloader) Count() int {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+	return len(this.urls)
+}
+
+// The newTestSpider returns a spider with a fake scheduler holding urls that logs nothing.
+func newTestSpider(processor processorFunc, urls ...string) (*Sipder, *fakeScheduler) {
+	sched := newFakeScheduler()
+	s := NewSpider(processor, "test").
+		SetScheduler(sched).
+		SetLogger(mlog.NewLogger(mlog.NewTextSink(ioutil.Discard)))
+	for _, url := range urls {
+		s.AddUrl(url, "html")
+	}
+	return s, sched
+}
+
+func TestSpiderStop(t *testing.T) {
+	stops := map[string]func(s *Sipder, cancel context.CancelFunc){
+		"cancel": func(s *Sipder, cancel context.CancelFunc) { cancel() },
+		"stop":   func(s *Sipder, cancel context.CancelFunc) { s.Stop() },
+	}
+	for name, stop := range stops {
+		started := make(chan string, 10)
+		release := make(chan struct{})
+		var finished int
+		var locker sync.Mutex
+		downloader := downloaderFunc(func(req *request.Request) *page.Page {
+			started <- req.GetUrl()
+			<-release
+			locker.Lock()
+			finished++
+			locker.Unlock()
+			return page.NewPage(req).SetStatusCode(200).SetBodyStr("<html></html>")
+		})
+
+		s, sched := newTestSpider(func(p *page.Page) {}, "http://a.com/1", "http://a.com/2")
+		s.SetDownloader(downloader).SetThreadnum(2).SetExitWhenComplete(false)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan int)
+		go func() {
+			s.RunContext(ctx)
+			locker.Lock()
+			done <- finished
+			locker.Unlock()
+		}()
+
+		<-started
+		<-started
+		stop(s, cancel)
+		select {
+		case <-done:
+			t.Errorf("%s: run returned before the requests in flight were finished", name)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		close(release)
+		select {
+		case n := <-done:
+			if n != 2 {
+				t.Errorf("%s: %d requests finished before run returned, want 2", name, n)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: run did not return", name)
+		}
+		if sched.Polled() != 2 {
+			t.Errorf("%s: %d requests polled, want 2", name, sched.Polled())
+		}
+		cancel()
+	}
+}
+
+func TestSpiderPauseResume(t *testing.T) {
+	downloader := &countDownloader{locker: new(sync.Mutex)}
+	s, _ := newTestSpider(func(p *page.Page) {}, "http://a.com/1", "http://a.com/2")
+	s.SetDownloader(downloader)
+
+	s.Pause()
+	if !s.IsPaused() {
+		t.Error("spider should be paused")
+	}
+	done := make(chan struct{})
+	go func() {
+		s.Run()
+		close(done)
+	}()
+
+	time.Sleep(100 * time.Millisecond)
+	if downloader.Count() != 0 {
+		t.Errorf("%d requests downloaded while paused", downloader.Count())
+	}
+
+	s.Resume()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after resume")
+	}
+	if s.IsPaused() || downloader.Count() != 2 {
+		t.Errorf("%d requests downloaded after resume, want 2", downloader.Count())
+	}
+}
+
+func TestSpiderMaxPages(t *testing.T) {
+	downloader := &countDownloader{locker: new(sync.Mutex)}
+	// every page links to a new page
+	s, sched := newTestSpider(func(p *page.Page) {
+		p.AddTargetRequest(p.GetRequest().GetUrl()+"/next", "html")
+	}, "http://a.com")
+	s.SetDownloader(downloader).SetMaxPages(3)
+	s.Run()
+
+	if downloader.Count() != 3 || sched.Polled() != 3 {
+		t.Errorf("%d requests downloaded, want 3", downloader.Count())
+	}
+}
+
+func TestSpiderMaxDepth(t *testing.T) {
+	downloader := &countDownloader{locker: new(sync.Mutex)}
+	// every page links to two new pages
+	s, _ := newTestSpider(func(p *page.Page) {
+		url := p.GetRequest().GetUrl()
+		p.AddTargetRequests([]string{url + "/0", url + "/1"}, "html")
+	}, "http://a.com")
+	s.SetDownloader(downloader).SetThreadnum(4).SetMaxDepth(2)
+	s.Run()
+
+	if downloader.Count() != 7 {
+		t.Errorf("%d requests downloaded, want 7", downloader.Count())
+	}
+	for _, url := range downloader.urls {
+		if strings.Count(url, "/") > 4 {
+			t.Errorf("%s is deeper than 2 links", url)
+		}
+	}
+}
+
+func TestSpiderTimeLimit(t *testing.T) {
+	s, _ := newTestSpider(func(p *page.Page) {})
+	s.SetExitWhenComplete(false).SetTimeLimit(100 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		s.Run()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after the time limit")
+	}
+}
+
+func TestSpiderClosePipelines(t *testing.T) {
+	pip := &recordPipeline{locker: new(sync.Mutex)}
+	s, _ := newTestSpider(func(p *page.Page) {}, "http://a.com")
+	s.SetDownloader(&countDownloader{locker: new(sync.Mutex)}).AddPipeline(pip)
+	s.Run()
+
+	if strings.Join(pip.calls, ",") != "process,flush,close" {
+		t.Errorf("pipeline calls error : %v", pip.calls)
+	}
+}
+
+func TestSpiderStopRetryBackoff(t *testing.T) {
+	started := make(chan struct{}, 10)
+	s, _ := newTestSpider(func(p *page.Page) {}, "http://a.com")
+	s.SetDownloader(downloaderFunc(func(req *request.Request) *page.Page {
+		started <- struct{}{}
+		p := page.NewPage(req).SetStatusCode(503)
+		p.SetStatus(true, "503 Service Unavailable")
+		return p
+	}))
+	s.SetRetryPolicy(&downloader.RetryPolicy{MaxAttempts: 3, RetryStatus: map[int]bool{503: true}, BaseDelay: time.Minute})
+	var errormsg string
+	s.OnError(func(req *request.Request, msg string) {
+		errormsg = msg
+	})
+
+	done := make(chan struct{})
+	go func() {
+		s.Run()
+		close(done)
+	}()
+	<-started
+	s.Stop()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run waited for the retry backoff after Stop")
+	}
+	if len(started) != 0 || errormsg != "503 Service Unavailable" {
+		t.Errorf("a stopped request should fail without another attempt : %d attempts, %q", len(started)+1, errormsg)
+	}
+}
+
+func TestSpiderCloseScheduler(t *testing.T) {
+	s, sched := newTestSpider(func(p *page.Page) {}, "http://a.com")
+	s.SetDownloader(&countDownloader{locker: new(sync.Mutex)})
+	s.Run()
+
+	if !sched.closed {
+		t.Error("the scheduler should be closed when the crawl ends")
+	}
+}
@@ -0,0 +1,174 @@
+// Package stats collects the statistics of a running crawl.
+package stats
+
+import (
+	"sync"
+	"time"
+)
+
+// HostStats are the counters of one host.
+// Failed counts the requests whose download failed, ProcessFailed the pages
+// downloaded but whose processing panicked and Skipped the pages skipped by the downloader,
+// such as unchanged pages answered with 304.
+type HostStats struct {
+	Downloaded    int64
+	Failed        int64
+	ProcessFailed int64
+	Skipped       int64
+	Retried       int64
+	Bytes         int64
+}
+
+// Snapshot is a copy of the statistics at one moment.
+type Snapshot struct {
+	StartTime time.Time
+	HostStats
+	// The Hosts holds the counters per host.
+	Hosts map[string]HostStats
+	// The StatusCodes holds how many responses were received per http status code.
+	StatusCodes map[int]int64
+	// The QueueDepth is the count of requests waiting in the scheduler.
+	QueueDepth int
+	Items      int64
+	// The PipelineCalls and PipelineTime are the count and the total time of Pipeline.Process calls.
+	PipelineCalls int64
+	PipelineTime  time.Duration
+}
+
+// PipelineLatency returns the average time of one Pipeline.Process call.
+func (this Snapshot) PipelineLatency() time.Duration {
+	if this.PipelineCalls == 0 {
+		return 0
+	}
+	return this.PipelineTime / time.Duration(this.PipelineCalls)
+}
+
+// Stats is a thread-safe set of crawl counters.
+type Stats struct {
+	locker        *sync.Mutex
+	startTime     time.Time
+	hosts         map[string]*HostStats
+	statusCodes   map[int]int64
+	items         int64
+	pipelineCalls int64
+	pipelineTime  time.Duration
+}
+
+func NewStats() *Stats {
+	this := &Stats{locker: new(sync.Mutex)}
+	this.Reset()
+	return this
+}
+
+// Reset clears all counters and restarts the clock.
+func (this *Stats) Reset() {
+	this.locker.Lock()
+	this.startTime = time.Now()
+	this.hosts = make(map[string]*HostStats)
+	this.statusCodes = make(map[int]int64)
+	this.items = 0
+	this.pipelineCalls = 0
+	this.pipelineTime = 0
+	this.locker.Unlock()
+}
+
+// AddDownload records a successful download of bytes from host.
+func (this *Stats) AddDownload(host string, statusCode int, bytes int) {
+	this.locker.Lock()
+	h := this.host(host)
+	h.Downloaded++
+	h.Bytes += int64(bytes)
+	this.addStatus(statusCode)
+	this.locker.Unlock()
+}
+
+// AddFailure records a request to host that failed after all retries.
+func (this *Stats) AddFailure(host string, statusCode int) {
+	this.locker.Lock()
+	this.host(host).Failed++
+	this.addStatus(statusCode)
+	this.locker.Unlock()
+}
+
+// AddProcessFailure records a page downloaded from host whose processing failed.
+func (this *Stats) AddProcessFailure(host string) {
+	this.locker.Lock()
+	this.host(host).ProcessFailed++
+	this.locker.Unlock()
+}
+
+// AddSkip records a page from host skipped by the downloader.
+func (this *Stats) AddSkip(host string, statusCode int) {
+	this.locker.Lock()
+	this.host(host).Skipped++
+	this.addStatus(statusCode)
+	this.locker.Unlock()
+}
+
+// AddRetry records a download to host that is attempted again.
+func (this *Stats) AddRetry(host string, statusCode int) {
+	this.locker.Lock()
+	this.host(host).Retried++
+	this.addStatus(statusCode)
+	this.locker.Unlock()
+}
+
+// AddItems records one PageItems sent to the pipelines.
+func (this *Stats) AddItems() {
+	this.locker.Lock()
+	this.items++
+	this.locker.Unlock()
+}
+
+// AddPipeline records the time of one Pipeline.Process call.
+func (this *Stats) AddPipeline(d time.Duration) {
+	this.locker.Lock()
+	this.pipelineCalls++
+	this.pipelineTime += d
+	this.locker.Unlock()
+}
+
+// Snapshot returns a copy of the counters with queueDepth filled in.
+func (this *Stats) Snapshot(queueDepth int) Snapshot {
+	this.locker.Lock()
+	defer this.locker.Unlock()
+
+	s := Snapshot{
+		StartTime:     this.startTime,
+		Hosts:         make(map[string]HostStats, len(this.hosts)),
+		StatusCodes:   make(map[int]int64, len(this.statusCodes)),
+		QueueDepth:    queueDepth,
+		Items:         this.items,
+		PipelineCalls: this.pipelineCalls,
+		PipelineTime:  this.pipelineTime,
+	}
+	for host, h := range this.hosts {
+		s.Hosts[host] = *h
+		s.Downloaded += h.Downloaded
+		s.Failed += h.Failed
+		s.ProcessFailed += h.ProcessFailed
+		s.Skipped += h.Skipped
+		s.Retried += h.Retried
+		s.Bytes += h.Bytes
+	}
+	for code, n := range this.statusCodes {
+		s.StatusCodes[code] = n
+	}
+	return s
+}
+
+func (this *Stats) host(host string) *HostStats {
+	h, ok := this.hosts[host]
+	if !ok {
+		h = &HostStats{}
+		this.hosts[host] = h
+	}
+	return h
+}
+
+// The addStatus counts statusCode, 0 means no response was received and is not counted.
+func (this *Stats) addStatus(statusCode int) {
+	if statusCode != 0 {
+		this.statusCodes[statusCode]++
+	}
+}
@@ -0,0 +1,43 @@
+package stats
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	s := NewStats()
+	s.AddDownload("a.com", 200, 100)
+	s.AddDownload("a.com", 200, 50)
+	s.AddRetry("b.com", 503)
+	s.AddFailure("b.com", 503)
+	s.AddProcessFailure("a.com")
+	s.AddSkip("a.com", 304)
+	s.AddItems()
+	s.AddPipeline(10 * time.Millisecond)
+	s.AddPipeline(30 * time.Millisecond)
+
+	snap := s.Snapshot(7)
+	fmt.Printf("%+v\n", snap)
+	if snap.Downloaded != 2 || snap.Bytes != 150 || snap.Failed != 1 || snap.ProcessFailed != 1 || snap.Skipped != 1 || snap.Retried != 1 {
+		t.Error("total counters error")
+	}
+	if snap.Hosts["a.com"].Downloaded != 2 || snap.Hosts["a.com"].ProcessFailed != 1 || snap.Hosts["b.com"].Failed != 1 {
+		t.Error("host counters error")
+	}
+	if snap.StatusCodes[200] != 2 || snap.StatusCodes[503] != 2 || snap.StatusCodes[304] != 1 {
+		t.Error("status codes error")
+	}
+	if snap.QueueDepth != 7 || snap.Items != 1 {
+		t.Error("queue depth or items error")
+	}
+	if snap.PipelineLatency() != 20*time.Millisecond {
+		t.Error("pipeline latency error")
+	}
+
+	s.Reset()
+	if snap = s.Snapshot(0); snap.Downloaded != 0 || len(snap.Hosts) != 0 {
+		t.Error("reset error")
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+)
+
+import (
+	"go_spider/core/page_processor"
+	"go_spider/core/pipeline"
+	"go_spider/core/spider"
+)
+
+func main() {
+	rules, err := page_processor.LoadRuleSet("rules.json")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	spider.NewSpider(page_processor.NewRuleProcessor(rules), "TaskName").
+		AddUrl("https://github.com/hu17889?tab=repositories", "html").
+		AddPipeline(pipeline.NewPipelineConsole()).
+		SetThreadnum(3).
+		Run()
+}